@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/rumsystem/rumchaindata/pkg/data"
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func runDecode(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("decode", flag.ContinueOnError)
+	var input inputFlags
+	input.register(fs, "trx", "trx, block, package, snapshot or seed")
+	cipherkey := fs.String("cipherkey", "", "hex group cipher key to decrypt and decode the data of a trx")
+	verify := fs.Bool("verify", false, "show the signature status of a trx")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	msg, err := input.readMessage(fs.Args())
+	if err != nil {
+		return err
+	}
+	if trx, ok := msg.(*quorumpb.Trx); ok {
+		if *verify {
+			cleanup, err := initVerifyKeystore()
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+		}
+		out, err := data.TrxToJSON(trx, &data.TrxJSONOptions{CipherKey: *cipherkey, Verify: *verify, ActivityStreams: true, Indent: "  "})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(stdout, string(out))
+		return err
+	}
+	if pkg, ok := msg.(*quorumpb.Package); ok {
+		return printPackage(stdout, pkg)
+	}
+	return printJSON(stdout, msg)
+}
+
+// the data of a package is printed decoded when its type is known
+func printPackage(w io.Writer, pkg *quorumpb.Package) error {
+	var inner proto.Message
+	switch pkg.Type {
+	case quorumpb.PackageType_TRX:
+		inner = &quorumpb.Trx{}
+	case quorumpb.PackageType_BLOCK:
+		inner = &quorumpb.Block{}
+	case quorumpb.PackageType_SNAPSHOT:
+		inner = &quorumpb.Snapshot{}
+	default:
+		return printJSON(w, pkg)
+	}
+	if err := proto.Unmarshal(pkg.Data, inner); err != nil {
+		return fmt.Errorf("decode %s package data failed: %s", pkg.Type, err)
+	}
+	innerjson, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(inner)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(map[string]interface{}{"type": pkg.Type.String(), "Data": json.RawMessage(innerjson)}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
+
+func runHash(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("hash", flag.ContinueOnError)
+	var input inputFlags
+	input.register(fs, "block", "block")
+	encoding := fs.String("encoding", "hex", "encoding of the printed hash: hex or base64")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if input.msgtype != "block" {
+		return fmt.Errorf("only the hash of a block is supported")
+	}
+	msg, err := input.readMessage(fs.Args())
+	if err != nil {
+		return err
+	}
+	block := msg.(*quorumpb.Block)
+	hash, err := data.BlockHash(block)
+	if err != nil {
+		return err
+	}
+	switch *encoding {
+	case "hex":
+		fmt.Fprintln(stdout, hex.EncodeToString(hash))
+	case "base64":
+		fmt.Fprintln(stdout, base64.StdEncoding.EncodeToString(hash))
+	default:
+		return fmt.Errorf("unknown encoding %q", *encoding)
+	}
+	if len(block.Hash) > 0 && !bytes.Equal(block.Hash, hash) {
+		fmt.Fprintf(stdout, "block %s: stored hash %s mismatch\n", block.BlockId, hex.EncodeToString(block.Hash))
+		return errVerifyFailed
+	}
+	return nil
+}
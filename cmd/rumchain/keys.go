@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	localcrypto "github.com/rumsystem/keystore/pkg/crypto"
+	"github.com/rumsystem/rumchaindata/pkg/data"
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+const PASSWORD_ENV = "RUM_KEYSTORE_PASSWORD"
+
+// keystoreFlags are the flags of the commands using a local keystore
+type keystoreFlags struct {
+	dir      string
+	keyname  string
+	password string
+}
+
+func (keystore *keystoreFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&keystore.dir, "keystore", "", "keystore directory")
+	fs.StringVar(&keystore.keyname, "key", "", "key name")
+	fs.StringVar(&keystore.password, "password", "", "keystore password, default to $"+PASSWORD_ENV)
+}
+
+// open inits the keystore and unlocks it with the addresses of all its sign keys
+func (keystore *keystoreFlags) open() (localcrypto.Keystore, map[string]string, error) {
+	if keystore.dir == "" || keystore.keyname == "" {
+		return nil, nil, fmt.Errorf("-keystore and -key are required")
+	}
+	if keystore.password == "" {
+		keystore.password = os.Getenv(PASSWORD_ENV)
+	}
+	if keystore.password == "" {
+		return nil, nil, fmt.Errorf("-password or $%s is required", PASSWORD_ENV)
+	}
+	if _, err := localcrypto.InitKeystore("rumchain", keystore.dir); err != nil {
+		return nil, nil, err
+	}
+	signkeymap, err := loadSignKeyMap(keystore.dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	ks := localcrypto.GetKeystore()
+	if err := ks.Unlock(signkeymap, keystore.password); err != nil {
+		return nil, nil, err
+	}
+	return ks, signkeymap, nil
+}
+
+// the address of a sign key is read from its key file
+func loadSignKeyMap(dir string) (map[string]string, error) {
+	signkeymap := make(map[string]string)
+	prefix := localcrypto.Sign.Prefix()
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasPrefix(f.Name(), prefix) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var keyfile struct {
+			Address string `json:"address"`
+		}
+		if err := json.Unmarshal(content, &keyfile); err != nil || keyfile.Address == "" {
+			continue
+		}
+		signkeymap[f.Name()[len(prefix):]] = keyfile.Address
+	}
+	return signkeymap, nil
+}
+
+func runKeygen(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	var keystore keystoreFlags
+	keystore.register(fs)
+	keytype := fs.String("type", "sign", "key type: sign or encrypt")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ks, signkeymap, err := keystore.open()
+	if err != nil {
+		return err
+	}
+
+	switch *keytype {
+	case "sign":
+		address, err := ks.NewKey(keystore.keyname, localcrypto.Sign, keystore.password)
+		if err != nil {
+			return err
+		}
+		signkeymap[keystore.keyname] = address
+		pubkey, err := ks.GetEncodedPubkey(keystore.keyname, localcrypto.Sign)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "key:     %s\ntype:    sign\naddress: %s\npubkey:  %s\n", keystore.keyname, address, pubkey)
+	case "encrypt":
+		pubkey, err := ks.NewKey(keystore.keyname, localcrypto.Encrypt, keystore.password)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "key:     %s\ntype:    encrypt\npubkey:  %s\n", keystore.keyname, pubkey)
+	default:
+		return fmt.Errorf("unknown key type %q", *keytype)
+	}
+	return nil
+}
+
+// runSign signs a trx, a block or a group seed as its sender, producer or owner, or signs a hash
+func runSign(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("sign", flag.ContinueOnError)
+	var keystore keystoreFlags
+	keystore.register(fs)
+	var input inputFlags
+	input.register(fs, "trx", "trx, block, seed or hash")
+	output := fs.String("output", "base64", "encoding of the signed trx or block: base64, hex or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var msg proto.Message
+	var hash []byte
+	if input.msgtype == "hash" {
+		raw, err := input.read(fs.Args())
+		if err != nil {
+			return err
+		}
+		if hash, _, err = decodeInput(raw, input.format); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if msg, err = input.readMessage(fs.Args()); err != nil {
+			return err
+		}
+	}
+
+	ks, _, err := keystore.open()
+	if err != nil {
+		return err
+	}
+	pubkey, err := ks.GetEncodedPubkey(keystore.keyname, localcrypto.Sign)
+	if err != nil {
+		return err
+	}
+
+	switch m := msg.(type) {
+	case nil:
+		signature, err := ks.EthSignByKeyName(keystore.keyname, hash)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, hex.EncodeToString(signature))
+		return nil
+	case *quorumpb.Trx:
+		m.SenderPubkey = pubkey
+		m.SenderSign = nil
+		hash, err := data.TrxHash(m)
+		if err != nil {
+			return err
+		}
+		if m.SenderSign, err = ks.EthSignByKeyName(keystore.keyname, hash); err != nil {
+			return err
+		}
+	case *quorumpb.Block:
+		m.ProducerPubKey = pubkey
+		hash, err := data.BlockHash(m)
+		if err != nil {
+			return err
+		}
+		m.Hash = hash
+		if m.Signature, err = ks.EthSignByKeyName(keystore.keyname, hash); err != nil {
+			return err
+		}
+	case *quorumpb.GroupSeed:
+		m.OwnerPubkey = pubkey
+		hash, err := data.GroupSeedHash(m)
+		if err != nil {
+			return err
+		}
+		signature, err := ks.EthSignByKeyName(keystore.keyname, hash)
+		if err != nil {
+			return err
+		}
+		m.Signature = hex.EncodeToString(signature)
+	default:
+		return fmt.Errorf("can not sign a %s", input.msgtype)
+	}
+
+	switch *output {
+	case "json":
+		return printJSON(stdout, msg)
+	case "base64", "hex":
+		encoded, err := proto.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		if *output == "hex" {
+			fmt.Fprintln(stdout, hex.EncodeToString(encoded))
+		} else {
+			fmt.Fprintln(stdout, base64.StdEncoding.EncodeToString(encoded))
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown output %q", *output)
+}
@@ -0,0 +1,210 @@
+// rumchain inspects, verifies and signs chain data: trxs, blocks, packages, snapshots and group seeds.
+//
+//	rumchain decode -type block -in block.bin
+//	rumchain verify -type chain -in blocks.txt
+//	rumchain hash <base64 block>
+//	rumchain keygen -keystore ./keys -key mykey
+//	rumchain sign -keystore ./keys -key mykey -type trx <hex trx>
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string, stdout io.Writer) error
+}
+
+var commands = []*command{
+	{"decode", "pretty print a trx, block, package, snapshot or seed", runDecode},
+	{"verify", "verify the signature of a trx, a block or a seed, or validate a chain of blocks", runVerify},
+	{"hash", "print the hash of a block", runHash},
+	{"keygen", "create a key in a local keystore", runKeygen},
+	{"sign", "sign a trx, a block, a seed or a hash with a key of a local keystore", runSign},
+}
+
+// errVerifyFailed makes rumchain exit with status 1 after the report is printed
+var errVerifyFailed = fmt.Errorf("verification failed")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		usage(stderr)
+		return 2
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			err := cmd.run(args[1:], stdout)
+			if err == flag.ErrHelp {
+				return 2
+			}
+			if err != nil {
+				fmt.Fprintf(stderr, "rumchain %s: %s\n", cmd.name, err)
+				return 1
+			}
+			return 0
+		}
+	}
+	fmt.Fprintf(stderr, "rumchain: unknown command %q\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: rumchain <command> [flags] [data]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintf(w, "\nrun rumchain <command> -h for the flags of a command\n")
+}
+
+// inputFlags are the flags shared by the commands reading chain data
+type inputFlags struct {
+	in      string
+	format  string
+	msgtype string
+}
+
+func (input *inputFlags) register(fs *flag.FlagSet, defaulttype string, types string) {
+	fs.StringVar(&input.in, "in", "", "read the data from a file, - for stdin, instead of the argument")
+	fs.StringVar(&input.format, "format", "auto", "encoding of the data: auto, base64, hex, json or raw")
+	fs.StringVar(&input.msgtype, "type", defaulttype, "type of the data: "+types)
+}
+
+// read returns the raw data given by the argument or the input file
+func (input *inputFlags) read(args []string) ([]byte, error) {
+	var raw []byte
+	var err error
+	switch {
+	case input.in == "-":
+		raw, err = io.ReadAll(os.Stdin)
+	case input.in != "":
+		raw, err = os.ReadFile(input.in)
+	case len(args) == 1:
+		raw = []byte(args[0])
+	default:
+		return nil, fmt.Errorf("expect the data as the only argument, or -in")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("empty input")
+	}
+	return raw, nil
+}
+
+// decode returns the data decoded from its format, and whether it is protojson
+func decodeInput(raw []byte, format string) ([]byte, bool, error) {
+	text := bytes.TrimSpace(raw)
+	switch format {
+	case "raw":
+		return raw, false, nil
+	case "json":
+		return text, true, nil
+	case "hex":
+		data, err := hex.DecodeString(string(text))
+		return data, false, err
+	case "base64":
+		data, err := decodeBase64(string(text))
+		return data, false, err
+	case "auto":
+		if !utf8.Valid(text) {
+			return raw, false, nil
+		}
+		if len(text) > 0 && (text[0] == '{' || text[0] == '[') {
+			return text, true, nil
+		}
+		if data, err := hex.DecodeString(string(text)); err == nil {
+			return data, false, nil
+		}
+		if data, err := decodeBase64(string(text)); err == nil {
+			return data, false, nil
+		}
+		return raw, false, nil
+	}
+	return nil, false, fmt.Errorf("unknown format %q", format)
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.Join(strings.Fields(s), "")
+	var err error
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		var data []byte
+		if data, err = encoding.DecodeString(s); err == nil {
+			return data, nil
+		}
+	}
+	return nil, err
+}
+
+func newMessage(msgtype string) (proto.Message, error) {
+	switch msgtype {
+	case "trx":
+		return &quorumpb.Trx{}, nil
+	case "block":
+		return &quorumpb.Block{}, nil
+	case "package":
+		return &quorumpb.Package{}, nil
+	case "snapshot":
+		return &quorumpb.Snapshot{}, nil
+	case "seed":
+		return &quorumpb.GroupSeed{}, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", msgtype)
+}
+
+// readMessage reads and unmarshals the message of the -type of input
+func (input *inputFlags) readMessage(args []string) (proto.Message, error) {
+	raw, err := input.read(args)
+	if err != nil {
+		return nil, err
+	}
+	data, isjson, err := decodeInput(raw, input.format)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalMessage(input.msgtype, data, isjson)
+}
+
+func unmarshalMessage(msgtype string, data []byte, isjson bool) (proto.Message, error) {
+	msg, err := newMessage(msgtype)
+	if err != nil {
+		return nil, err
+	}
+	if isjson {
+		err = protojson.Unmarshal(data, msg)
+	} else {
+		err = proto.Unmarshal(data, msg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode %s failed: %s", msgtype, err)
+	}
+	return msg, nil
+}
+
+func printJSON(w io.Writer, msg proto.Message) error {
+	out, err := protojson.MarshalOptions{Multiline: true, Indent: "  ", UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rumsystem/rumchaindata/pkg/data"
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+func runCmd(t *testing.T, expect int, args ...string) string {
+	var stdout, stderr bytes.Buffer
+	if code := run(args, &stdout, &stderr); code != expect {
+		t.Fatalf("rumchain %s: exit %d, expect %d\n%s%s", strings.Join(args, " "), code, expect, stdout.String(), stderr.String())
+	}
+	return stdout.String()
+}
+
+func encodeMessage(t *testing.T, msg proto.Message) string {
+	return base64.StdEncoding.EncodeToString(mustMarshal(t, msg))
+}
+
+func decodeMessage(t *testing.T, s string, msg proto.Message) {
+	encoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := proto.Unmarshal(encoded, msg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRumchain(t *testing.T) {
+	dir := t.TempDir()
+	keyflags := []string{"-keystore", filepath.Join(dir, "keystore"), "-key", "producer", "-password", "password"}
+
+	out := runCmd(t, 0, append([]string{"keygen"}, keyflags...)...)
+	if !strings.Contains(out, "pubkey:") {
+		t.Fatalf("unexpected keygen output: %s", out)
+	}
+
+	trx := &quorumpb.Trx{TrxId: "trx1", GroupId: "group1", Data: []byte("data"), TimeStamp: 1}
+	signedtrx := runCmd(t, 0, append(append([]string{"sign"}, keyflags...), "-type", "trx", encodeMessage(t, trx))...)
+	runCmd(t, 0, "verify", "-type", "trx", strings.TrimSpace(signedtrx))
+
+	genesis := &quorumpb.Block{}
+	decodeMessage(t, runCmd(t, 0, append(append([]string{"sign"}, keyflags...), "-type", "block", encodeMessage(t, &quorumpb.Block{BlockId: "block0", GroupId: "group1"}))...), genesis)
+	signed := &quorumpb.Trx{}
+	decodeMessage(t, signedtrx, signed)
+	block := &quorumpb.Block{BlockId: "block1", GroupId: "group1", PrevBlockId: genesis.BlockId, PreviousHash: genesis.Hash, Trxs: []*quorumpb.Trx{signed}}
+	signedblock := runCmd(t, 0, append(append([]string{"sign"}, keyflags...), "-type", "block", encodeMessage(t, block))...)
+	block = &quorumpb.Block{}
+	decodeMessage(t, signedblock, block)
+
+	runCmd(t, 0, "verify", "-type", "block", encodeMessage(t, block))
+	out = runCmd(t, 0, "hash", encodeMessage(t, block))
+	if strings.TrimSpace(out) != hex.EncodeToString(block.Hash) {
+		t.Errorf("hash %s, expect %s", out, hex.EncodeToString(block.Hash))
+	}
+
+	chainfile := filepath.Join(dir, "chain.txt")
+	os.WriteFile(chainfile, []byte(encodeMessage(t, genesis)+"\n"+encodeMessage(t, block)+"\n"), 0600)
+	runCmd(t, 0, "verify", "-type", "chain", "-in", chainfile)
+
+	var archive bytes.Buffer
+	seed := &quorumpb.GroupSeed{}
+	decodeMessage(t, runCmd(t, 0, append(append([]string{"sign"}, keyflags...), "-type", "seed", encodeMessage(t, &quorumpb.GroupSeed{GenesisBlock: genesis, GroupId: "group1"}))...), seed)
+	runCmd(t, 0, "verify", "-type", "seed", encodeMessage(t, seed))
+	writer, err := data.NewArchiveWriter(&archive, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	writer.Close()
+	archivefile := filepath.Join(dir, "group1.archive")
+	os.WriteFile(archivefile, archive.Bytes(), 0600)
+	runCmd(t, 0, "verify", "-type", "archive", "-in", archivefile)
+	os.WriteFile(archivefile, archive.Bytes()[:archive.Len()-1], 0600)
+	runCmd(t, 1, "verify", "-type", "archive", "-in", archivefile)
+
+	//a block which does not follow the previous one
+	block.PreviousHash = []byte("bad hash")
+	os.WriteFile(chainfile, []byte(encodeMessage(t, genesis)+"\n"+encodeMessage(t, block)+"\n"), 0600)
+	out = runCmd(t, 1, "verify", "-type", "chain", "-in", chainfile)
+	if !strings.Contains(out, "FAILED  block block1 follows block block0") {
+		t.Errorf("unexpected verify output: %s", out)
+	}
+	runCmd(t, 1, "hash", encodeMessage(t, block))
+
+	pkg := &quorumpb.Package{Type: quorumpb.PackageType_BLOCK, Data: mustMarshal(t, genesis)}
+	out = runCmd(t, 0, "decode", "-type", "package", "-format", "hex", hex.EncodeToString(mustMarshal(t, pkg)))
+	if !strings.Contains(out, `"BlockId": "block0"`) || !strings.Contains(out, `"type": "BLOCK"`) {
+		t.Errorf("unexpected decode output: %s", out)
+	}
+
+	runCmd(t, 2, "unknown")
+}
+
+func mustMarshal(t *testing.T, msg proto.Message) []byte {
+	encoded, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encoded
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	localcrypto "github.com/rumsystem/keystore/pkg/crypto"
+	"github.com/rumsystem/rumchaindata/pkg/data"
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+)
+
+func runVerify(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	var input inputFlags
+	input.register(fs, "trx", "trx, block, seed, chain (blocks in height order, one per line or a json array) or archive")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cleanup, err := initVerifyKeystore()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	report := &verifyReport{w: stdout}
+	switch input.msgtype {
+	case "archive":
+		if err := report.archive(input.in); err != nil {
+			return err
+		}
+	case "chain":
+		raw, err := input.read(fs.Args())
+		if err != nil {
+			return err
+		}
+		blocks, err := readBlocks(raw, input.format)
+		if err != nil {
+			return err
+		}
+		for i, block := range blocks {
+			if i == 0 {
+				report.block(block)
+				continue
+			}
+			ok, err := data.IsBlockValid(block, blocks[i-1])
+			report.result(fmt.Sprintf("block %s follows block %s", block.BlockId, blocks[i-1].BlockId), ok, err)
+			report.trxs(block)
+		}
+	default:
+		msg, err := input.readMessage(fs.Args())
+		if err != nil {
+			return err
+		}
+		switch m := msg.(type) {
+		case *quorumpb.Trx:
+			report.trx(m)
+		case *quorumpb.Block:
+			report.block(m)
+		case *quorumpb.GroupSeed:
+			report.seed(m)
+		default:
+			return fmt.Errorf("can not verify a %s", input.msgtype)
+		}
+	}
+	if report.failed > 0 {
+		fmt.Fprintf(stdout, "%d check(s) failed\n", report.failed)
+		return errVerifyFailed
+	}
+	return nil
+}
+
+// the verification of eth signatures goes through the keystore, a throwaway one is enough
+func initVerifyKeystore() (func(), error) {
+	if localcrypto.GetKeystore() != nil {
+		return func() {}, nil
+	}
+	dir, err := os.MkdirTemp("", "rumchain-verify")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := localcrypto.InitKeystore("rumchain", dir); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return func() { os.RemoveAll(dir) }, nil
+}
+
+// blocks are one per line in any of the input formats, or a json array
+func readBlocks(raw []byte, format string) ([]*quorumpb.Block, error) {
+	text := bytes.TrimSpace(raw)
+	var items [][]byte
+	isjson := false
+	if len(text) > 0 && text[0] == '[' {
+		var array []json.RawMessage
+		if err := json.Unmarshal(text, &array); err != nil {
+			return nil, err
+		}
+		for _, item := range array {
+			items = append(items, item)
+		}
+		isjson = true
+	} else {
+		if format == "raw" {
+			return nil, fmt.Errorf("a chain can not be read in raw format")
+		}
+		for _, line := range bytes.Split(text, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				items = append(items, line)
+			}
+		}
+	}
+
+	blocks := make([]*quorumpb.Block, 0, len(items))
+	for i, item := range items {
+		blockdata, itemjson := item, isjson
+		if !isjson {
+			var err error
+			if blockdata, itemjson, err = decodeInput(item, format); err != nil {
+				return nil, fmt.Errorf("block %d: %s", i, err)
+			}
+		}
+		msg, err := unmarshalMessage("block", blockdata, itemjson)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %s", i, err)
+		}
+		blocks = append(blocks, msg.(*quorumpb.Block))
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("no block")
+	}
+	return blocks, nil
+}
+
+type verifyReport struct {
+	w      io.Writer
+	failed int
+}
+
+func (report *verifyReport) result(check string, ok bool, err error) {
+	switch {
+	case ok && err == nil:
+		fmt.Fprintf(report.w, "ok      %s\n", check)
+	case err != nil:
+		report.failed++
+		fmt.Fprintf(report.w, "FAILED  %s: %s\n", check, err)
+	default:
+		report.failed++
+		fmt.Fprintf(report.w, "FAILED  %s\n", check)
+	}
+}
+
+func (report *verifyReport) trx(trx *quorumpb.Trx) {
+	if len(trx.SenderSign) == 0 {
+		report.result(fmt.Sprintf("trx %s signature", trx.TrxId), false, fmt.Errorf("not signed"))
+		return
+	}
+	ok, err := data.VerifyTrx(trx)
+	report.result(fmt.Sprintf("trx %s signature", trx.TrxId), ok, err)
+}
+
+func (report *verifyReport) trxs(block *quorumpb.Block) {
+	for _, trx := range block.Trxs {
+		report.trx(trx)
+	}
+}
+
+func (report *verifyReport) block(block *quorumpb.Block) {
+	hash, err := data.BlockHash(block)
+	report.result(fmt.Sprintf("block %s hash", block.BlockId), err == nil && bytes.Equal(hash, block.Hash), err)
+	if len(block.Signature) == 0 {
+		report.result(fmt.Sprintf("block %s signature", block.BlockId), false, fmt.Errorf("not signed"))
+	} else {
+		ok, err := data.VerifyBlockSign(block)
+		report.result(fmt.Sprintf("block %s signature", block.BlockId), ok, err)
+	}
+	report.trxs(block)
+}
+
+func (report *verifyReport) seed(seed *quorumpb.GroupSeed) {
+	genesis := seed.GenesisBlock
+	if genesis == nil {
+		report.result(fmt.Sprintf("seed of group %s", seed.GroupId), false, fmt.Errorf("no genesis block"))
+		return
+	}
+	report.result(fmt.Sprintf("genesis block %s is of group %s", genesis.BlockId, seed.GroupId), genesis.GroupId == seed.GroupId && genesis.PrevBlockId == "", nil)
+	report.result(fmt.Sprintf("genesis block %s is produced by owner %s", genesis.BlockId, seed.OwnerPubkey), genesis.ProducerPubKey == seed.OwnerPubkey, nil)
+	report.block(genesis)
+	ok, err := data.VerifyGroupSeed(seed)
+	report.result(fmt.Sprintf("seed of group %s is signed by owner %s", seed.GroupId, seed.OwnerPubkey), ok, err)
+}
+
+// the archive is streamed from its file, the reader validates the blocks
+func (report *verifyReport) archive(path string) error {
+	if path == "" || path == "-" {
+		return fmt.Errorf("-in is required to verify an archive")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader, err := data.NewArchiveReader(bufio.NewReader(f))
+	if err != nil {
+		report.result("archive header and group seed", false, err)
+		return nil
+	}
+	report.result(fmt.Sprintf("seed of group %s", reader.Seed().GroupId), true, nil)
+	count := 0
+	for {
+		block, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			report.result(fmt.Sprintf("archive after %d block(s)", count), false, err)
+			return nil
+		}
+		count++
+		report.trxs(block)
+	}
+	report.result(fmt.Sprintf("%d block(s) and checksum", count), true, nil)
+	if snapshot := reader.Snapshot(); snapshot != nil {
+		report.result(fmt.Sprintf("snapshot %s", snapshot.SnapshotId), true, nil)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package activitypub
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rumsystem/rumchaindata/pkg/data"
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	ContentType     = "application/activity+json"
+	PublicIRI       = quorumpb.ActivityStreamsContext + "#Public"
+	securityContext = "https://w3id.org/security/v1"
+	usersPath       = "/users/"
+	groupsPath      = "/groups/"
+	trxsPath        = "/trxs/"
+	activitySuffix  = "/activity"
+)
+
+// Bridge maps the POST trxs of a group to ActivityPub activities and back.
+// IRIs are built under BaseURL:
+//
+//	actor    <BaseURL>/users/<sender pubkey>
+//	group    <BaseURL>/groups/<group id>
+//	post     <BaseURL>/trxs/<trx id>
+//	activity <BaseURL>/trxs/<trx id>/activity
+type Bridge struct {
+	BaseURL string
+	GroupId string
+	// Public adds the as:Public collection to the recipients of outgoing activities
+	Public bool
+	// GroupBaseURLs gives the BaseURL of the bridges of other groups, by group id, to link replies to
+	// their posts. A reply to a post of a group without a bridge has no inReplyTo.
+	GroupBaseURLs map[string]string
+}
+
+func NewBridge(baseURL string, groupId string, public bool) (*Bridge, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return nil, fmt.Errorf("base url %s must be http or https", baseURL)
+	}
+	return &Bridge{BaseURL: strings.TrimRight(baseURL, "/"), GroupId: groupId, Public: public}, nil
+}
+
+func (bridge *Bridge) ActorIRI(pubkey string) string {
+	return bridge.BaseURL + usersPath + url.PathEscape(pubkey)
+}
+
+func (bridge *Bridge) GroupIRI() string {
+	return bridge.BaseURL + groupsPath + url.PathEscape(bridge.GroupId)
+}
+
+func (bridge *Bridge) TrxIRI(trxid string) string {
+	return bridge.BaseURL + trxsPath + url.PathEscape(trxid)
+}
+
+// replyIRI returns the Reply of an outgoing object, with the IRI of the post replied to,
+// or nil if the post has no IRI
+func (bridge *Bridge) replyIRI(reply *quorumpb.Reply) *quorumpb.Reply {
+	if reply.Trxid == "" {
+		return nil
+	}
+	if strings.Contains(reply.Trxid, "://") {
+		return &quorumpb.Reply{Trxid: reply.Trxid}
+	}
+	if reply.Groupid == "" || reply.Groupid == bridge.GroupId {
+		return &quorumpb.Reply{Trxid: bridge.TrxIRI(reply.Trxid)}
+	}
+	baseURL, ok := bridge.GroupBaseURLs[reply.Groupid]
+	if !ok {
+		return nil
+	}
+	return &quorumpb.Reply{Trxid: strings.TrimRight(baseURL, "/") + trxsPath + url.PathEscape(reply.Trxid)}
+}
+
+// TrxIdFromIRI returns the trx id of a post IRI of this bridge
+func (bridge *Bridge) TrxIdFromIRI(iri string) (string, bool) {
+	prefix := bridge.BaseURL + trxsPath
+	if !strings.HasPrefix(iri, prefix) {
+		return "", false
+	}
+	trxid, err := url.PathUnescape(strings.TrimSuffix(strings.TrimPrefix(iri, prefix), activitySuffix))
+	if err != nil || trxid == "" || strings.Contains(trxid, "/") {
+		return "", false
+	}
+	return trxid, true
+}
+
+// TrxToActivity turns a POST trx and its decrypted data into an ActivityPub activity.
+// An Object, or an Activity of type Add or Create, becomes a Create activity, other activities keep their type.
+func (bridge *Bridge) TrxToActivity(trx *quorumpb.Trx, trxdata []byte) (*quorumpb.Activity, error) {
+	if trx.Type != quorumpb.TrxType_POST {
+		return nil, fmt.Errorf("trx %s is %s, not %s", trx.TrxId, trx.Type, quorumpb.TrxType_POST)
+	}
+	if trx.GroupId != bridge.GroupId {
+		return nil, fmt.Errorf("trx %s for group %s, expect group %s", trx.TrxId, trx.GroupId, bridge.GroupId)
+	}
+	content, _, err := data.DecodePostContent(trx.TrxId, trxdata)
+	if err != nil {
+		return nil, err
+	}
+
+	activity := &quorumpb.Activity{Type: "Create"}
+	var obj *quorumpb.Object
+	switch c := content.(type) {
+	case *quorumpb.Object:
+		obj = proto.Clone(c).(*quorumpb.Object)
+	case *quorumpb.Activity:
+		activity = proto.Clone(c).(*quorumpb.Activity)
+		if activity.Type == "Add" || activity.Type == "" {
+			activity.Type = "Create"
+		}
+		activity.Target = nil
+		obj = activity.Object
+	default:
+		return nil, fmt.Errorf("unsupported content %T of trx %s", content, trx.TrxId)
+	}
+
+	actor := bridge.ActorIRI(trx.SenderPubkey)
+	published := timestamppb.New(time.Unix(0, trx.TimeStamp))
+	activity.Id = bridge.TrxIRI(trx.TrxId) + activitySuffix
+	activity.Actor = &quorumpb.Object{Id: actor}
+	activity.Audience = &quorumpb.Object{Id: bridge.GroupIRI()}
+	activity.Published = published
+	activity.To, activity.Cc = bridge.recipients()
+
+	if obj != nil && activity.Type == "Create" {
+		obj.Id = bridge.TrxIRI(trx.TrxId)
+		obj.AttributedTo = []*quorumpb.Object{{Id: actor}}
+		obj.Audience = &quorumpb.Object{Id: bridge.GroupIRI()}
+		obj.To, obj.Cc = bridge.recipients()
+		if obj.Published == nil {
+			obj.Published = published
+		}
+		if obj.Inreplyto != nil {
+			obj.Inreplyto = bridge.replyIRI(obj.Inreplyto)
+		}
+		activity.Object = obj
+	}
+	return activity, nil
+}
+
+// TrxToJSONLD is TrxToActivity rendered as JSON-LD
+func (bridge *Bridge) TrxToJSONLD(trx *quorumpb.Trx, trxdata []byte) ([]byte, error) {
+	activity, err := bridge.TrxToActivity(trx, trxdata)
+	if err != nil {
+		return nil, err
+	}
+	return quorumpb.ToJSONLD(activity)
+}
+
+// ActivityToPost converts an inbound Create activity into the Activity to pass to TrxFactory.GetPostAnyTrx:
+// an Add of the created object to the group, with replies to posts of this bridge mapped back to Reply trx ids
+func (bridge *Bridge) ActivityToPost(data []byte) (*quorumpb.Activity, error) {
+	inbound := &quorumpb.Activity{}
+	if err := quorumpb.FromJSONLD(data, inbound); err != nil {
+		return nil, err
+	}
+	if inbound.Type != "Create" {
+		return nil, fmt.Errorf("unsupported activity type %s", inbound.Type)
+	}
+	if inbound.Object == nil || inbound.Object.Type == "" {
+		return nil, fmt.Errorf("create activity %s has no object", inbound.Id)
+	}
+
+	obj := proto.Clone(inbound.Object).(*quorumpb.Object)
+	if len(obj.AttributedTo) == 0 && inbound.Actor != nil {
+		obj.AttributedTo = []*quorumpb.Object{inbound.Actor}
+	}
+	if obj.Inreplyto != nil {
+		if trxid, ok := bridge.TrxIdFromIRI(obj.Inreplyto.Trxid); ok {
+			obj.Inreplyto = &quorumpb.Reply{Trxid: trxid, Groupid: bridge.GroupId}
+		}
+	}
+	//recipients are rebuilt on the way out
+	obj.To, obj.Bto, obj.Cc, obj.Bcc, obj.Audience = nil, nil, nil, nil, nil
+
+	return &quorumpb.Activity{
+		Type:   "Add",
+		Actor:  inbound.Actor,
+		Object: obj,
+		Target: &quorumpb.Object{Id: bridge.GroupId, Type: "Group"},
+	}, nil
+}
+
+func (bridge *Bridge) recipients() (to []*quorumpb.Object, cc []*quorumpb.Object) {
+	followers := &quorumpb.Object{Id: bridge.GroupIRI() + "/followers"}
+	if bridge.Public {
+		return []*quorumpb.Object{{Id: PublicIRI}}, []*quorumpb.Object{followers}
+	}
+	return []*quorumpb.Object{followers}, nil
+}
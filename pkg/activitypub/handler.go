@@ -0,0 +1,353 @@
+package activitypub
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+)
+
+const (
+	INBOX_SIZE_LIMIT     = 1024 * 1024 //(1Mb)
+	COLLECTION_PAGE_SIZE = 20          //items of a page of the outbox and followers collections
+)
+
+var ErrPostNotFound = errors.New("post not found")
+
+// PostStore gives the POST trxs of the group with their decrypted data
+type PostStore interface {
+	GetPost(trxid string) (*quorumpb.Trx, []byte, error)
+	// ListPosts returns the trx ids of at most limit posts from offset, newest first, and the number of posts
+	ListPosts(offset int, limit int) ([]string, int, error)
+}
+
+// Handler serves the ActivityPub endpoints of a bridged group under the path of Bridge.BaseURL:
+//
+//	GET  /groups/<group id>            the group actor
+//	POST /groups/<group id>/inbox      inbound Create and Follow activities
+//	GET  /groups/<group id>/outbox     the Create activities of the posts, paged with ?page=<n>
+//	GET  /groups/<group id>/followers  the followers of the group, paged with ?page=<n>
+//	GET  /trxs/<trx id>                a post
+//	GET  /trxs/<trx id>/activity       the Create activity of a post
+//
+// Inbound requests are not authenticated here, HTTP signatures must be checked by a middleware.
+type Handler struct {
+	Bridge *Bridge
+	Store  PostStore
+	// PublicKey is published on the group actor as <group IRI>#main-key, the key the HTTP signatures of
+	// the activities sent by Deliver are verified with
+	PublicKey crypto.PublicKey
+	// OnPost receives inbound Create activities converted by Bridge.ActivityToPost,
+	// typically to publish them with TrxFactory.GetPostAnyTrx
+	OnPost func(activity *quorumpb.Activity) error
+	// OnFollow receives the actor IRI of inbound Follow activities, nil to reject follows
+	OnFollow func(actor string) error
+	// Followers returns the actor IRIs of the followers of the group
+	Followers func() ([]string, error)
+	// Deliver signs and sends an activity of the group actor to the inbox of actor, follows are rejected
+	// if nil since a Follow must be answered with an Accept
+	Deliver func(actor string, activity []byte) error
+}
+
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	base, err := url.Parse(handler.Bridge.BaseURL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	//r.URL.Path is already unescaped, the escaped path is split first so each id is unescaped once
+	path := strings.TrimPrefix(r.URL.EscapedPath(), strings.TrimRight(base.EscapedPath(), "/"))
+	groupid, groupres, isgroup := pathSegment(path, groupsPath)
+	isgroup = isgroup && groupid == handler.Bridge.GroupId
+	trxid, trxres, istrx := pathSegment(path, trxsPath)
+
+	switch {
+	case isgroup && groupres == "" && r.Method == http.MethodGet:
+		handler.serveGroup(w)
+	case isgroup && groupres == "/inbox" && r.Method == http.MethodPost:
+		handler.serveInbox(w, r)
+	case isgroup && groupres == "/outbox" && r.Method == http.MethodGet:
+		handler.serveOutbox(w, r)
+	case isgroup && groupres == "/followers" && r.Method == http.MethodGet:
+		handler.serveFollowers(w, r)
+	case istrx && (trxres == "" || trxres == activitySuffix) && r.Method == http.MethodGet:
+		handler.servePost(w, r, trxid, trxres == activitySuffix)
+	case isgroup && (groupres == "" || groupres == "/inbox" || groupres == "/outbox" || groupres == "/followers"),
+		istrx && (trxres == "" || trxres == activitySuffix):
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// pathSegment splits an escaped path prefix/<segment><rest> into the unescaped segment and the rest,
+// ok is false if path does not start with prefix or the segment is empty or badly escaped
+func pathSegment(path string, prefix string) (segment string, rest string, ok bool) {
+	if !strings.HasPrefix(path, prefix) {
+		return "", "", false
+	}
+	escaped := strings.TrimPrefix(path, prefix)
+	if i := strings.Index(escaped, "/"); i >= 0 {
+		escaped, rest = escaped[:i], escaped[i:]
+	}
+	segment, err := url.PathUnescape(escaped)
+	if err != nil || segment == "" {
+		return "", "", false
+	}
+	return segment, rest, true
+}
+
+func (handler *Handler) serveGroup(w http.ResponseWriter) {
+	groupiri := handler.Bridge.GroupIRI()
+	doc := map[string]interface{}{
+		"@context":          []interface{}{quorumpb.ActivityStreamsContext, securityContext},
+		"id":                groupiri,
+		"type":              "Group",
+		"preferredUsername": handler.Bridge.GroupId,
+		"inbox":             groupiri + "/inbox",
+		"outbox":            groupiri + "/outbox",
+		"followers":         groupiri + "/followers",
+	}
+	if handler.PublicKey != nil {
+		der, err := x509.MarshalPKIXPublicKey(handler.PublicKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		doc["publicKey"] = map[string]interface{}{
+			"id":           groupiri + "#main-key",
+			"owner":        groupiri,
+			"publicKeyPem": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		}
+	}
+	writeJSON(w, http.StatusOK, doc)
+}
+
+func (handler *Handler) serveOutbox(w http.ResponseWriter, r *http.Request) {
+	if handler.Store == nil {
+		http.NotFound(w, r)
+		return
+	}
+	handler.serveCollection(w, r, handler.Bridge.GroupIRI()+"/outbox", func(offset, limit int) ([]interface{}, int, error) {
+		trxids, total, err := handler.Store.ListPosts(offset, limit)
+		if err != nil {
+			return nil, 0, err
+		}
+		items := make([]interface{}, 0, len(trxids))
+		for _, trxid := range trxids {
+			trx, trxdata, err := handler.Store.GetPost(trxid)
+			if err != nil {
+				return nil, 0, err
+			}
+			activity, err := handler.Bridge.TrxToActivity(trx, trxdata)
+			if err != nil {
+				return nil, 0, err
+			}
+			item, err := quorumpb.ToJSONLDMap(activity)
+			if err != nil {
+				return nil, 0, err
+			}
+			delete(item, "@context")
+			items = append(items, item)
+		}
+		return items, total, nil
+	})
+}
+
+func (handler *Handler) serveFollowers(w http.ResponseWriter, r *http.Request) {
+	handler.serveCollection(w, r, handler.Bridge.GroupIRI()+"/followers", func(offset, limit int) ([]interface{}, int, error) {
+		var followers []string
+		if handler.Followers != nil {
+			var err error
+			if followers, err = handler.Followers(); err != nil {
+				return nil, 0, err
+			}
+		}
+		items := []interface{}{}
+		for i := offset; i < len(followers) && i < offset+limit; i++ {
+			items = append(items, followers[i])
+		}
+		return items, len(followers), nil
+	})
+}
+
+// serveCollection serves an OrderedCollection without the query string, and its
+// OrderedCollectionPage number n, from 1, with ?page=<n>
+func (handler *Handler) serveCollection(w http.ResponseWriter, r *http.Request, iri string, items func(offset, limit int) ([]interface{}, int, error)) {
+	pageparam := r.URL.Query().Get("page")
+	if pageparam == "" {
+		_, total, err := items(0, 0)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"@context":   quorumpb.ActivityStreamsContext,
+			"id":         iri,
+			"type":       "OrderedCollection",
+			"totalItems": total,
+			"first":      iri + "?page=1",
+		})
+		return
+	}
+
+	page, err := strconv.Atoi(pageparam)
+	if err != nil || page < 1 {
+		http.Error(w, "invalid page "+pageparam, http.StatusBadRequest)
+		return
+	}
+	pageitems, total, err := items((page-1)*COLLECTION_PAGE_SIZE, COLLECTION_PAGE_SIZE)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	doc := map[string]interface{}{
+		"@context":     quorumpb.ActivityStreamsContext,
+		"id":           fmt.Sprintf("%s?page=%d", iri, page),
+		"type":         "OrderedCollectionPage",
+		"partOf":       iri,
+		"totalItems":   total,
+		"orderedItems": pageitems,
+	}
+	if page > 1 {
+		doc["prev"] = fmt.Sprintf("%s?page=%d", iri, page-1)
+	}
+	if page*COLLECTION_PAGE_SIZE < total {
+		doc["next"] = fmt.Sprintf("%s?page=%d", iri, page+1)
+	}
+	writeJSON(w, http.StatusOK, doc)
+}
+
+func (handler *Handler) servePost(w http.ResponseWriter, r *http.Request, trxid string, asactivity bool) {
+	if handler.Store == nil {
+		http.NotFound(w, r)
+		return
+	}
+	trx, data, err := handler.Store.GetPost(trxid)
+	if errors.Is(err, ErrPostNotFound) || (err == nil && trx.GroupId != handler.Bridge.GroupId) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	activity, err := handler.Bridge.TrxToActivity(trx, data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var doc map[string]interface{}
+	if asactivity || activity.Object == nil {
+		doc, err = quorumpb.ToJSONLDMap(activity)
+	} else {
+		doc, err = quorumpb.ToJSONLDMap(activity.Object)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, doc)
+}
+
+func (handler *Handler) serveInbox(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, INBOX_SIZE_LIMIT+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body) > INBOX_SIZE_LIMIT {
+		http.Error(w, "activity size over 1Mb", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var head struct {
+		Type  string      `json:"type"`
+		Actor interface{} `json:"actor"`
+	}
+	if err := json.Unmarshal(body, &head); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch head.Type {
+	case "Create":
+		if handler.OnPost == nil {
+			http.Error(w, "posts are not accepted", http.StatusForbidden)
+			return
+		}
+		activity, err := handler.Bridge.ActivityToPost(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if err := handler.OnPost(activity); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+	case "Follow":
+		if handler.OnFollow == nil || handler.Deliver == nil {
+			http.Error(w, "follows are not accepted", http.StatusForbidden)
+			return
+		}
+		actor, ok := head.Actor.(string)
+		if !ok {
+			if obj, isobj := head.Actor.(map[string]interface{}); isobj {
+				actor, ok = obj["id"].(string)
+			}
+		}
+		if !ok || actor == "" {
+			http.Error(w, "follow activity has no actor", http.StatusBadRequest)
+			return
+		}
+		if err := handler.OnFollow(actor); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if err := handler.Deliver(actor, handler.acceptFollow(body)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+	default:
+		http.Error(w, "unsupported activity type "+head.Type, http.StatusNotImplemented)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// acceptFollow returns the Accept activity of the group actor for an inbound Follow activity
+func (handler *Handler) acceptFollow(follow []byte) []byte {
+	groupiri := handler.Bridge.GroupIRI()
+	hash := sha256.Sum256(follow)
+	accept, _ := json.Marshal(map[string]interface{}{
+		"@context": quorumpb.ActivityStreamsContext,
+		"id":       groupiri + "#accepts/" + hex.EncodeToString(hash[:]),
+		"type":     "Accept",
+		"actor":    groupiri,
+		"object":   json.RawMessage(follow),
+	})
+	return accept
+}
+
+func writeJSON(w http.ResponseWriter, status int, doc interface{}) {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	w.Write(body)
+}
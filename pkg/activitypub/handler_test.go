@@ -0,0 +1,359 @@
+package activitypub
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/rumsystem/rumchaindata/pkg/data"
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+const testGroupId = "7c352591-f237-4b80-81fb-d6347d0380b5"
+
+type testStore map[string]*quorumpb.Trx
+
+func (store testStore) GetPost(trxid string) (*quorumpb.Trx, []byte, error) {
+	trx, ok := store[trxid]
+	if !ok {
+		return nil, nil, ErrPostNotFound
+	}
+	return trx, trx.Data, nil
+}
+
+func (store testStore) ListPosts(offset int, limit int) ([]string, int, error) {
+	trxids := make([]string, 0, len(store))
+	for trxid := range store {
+		trxids = append(trxids, trxid)
+	}
+	sort.Slice(trxids, func(i, j int) bool { return store[trxids[i]].TimeStamp > store[trxids[j]].TimeStamp })
+	if offset > len(trxids) {
+		offset = len(trxids)
+	}
+	if offset+limit < len(trxids) {
+		return trxids[offset : offset+limit], len(trxids), nil
+	}
+	return trxids[offset:], len(trxids), nil
+}
+
+func newTestServer(t *testing.T, posts *[]*quorumpb.Activity) (*httptest.Server, *Bridge) {
+	server, bridge, _ := newTestServerWithStore(t, posts)
+	return server, bridge
+}
+
+func newTestServerWithStore(t *testing.T, posts *[]*quorumpb.Activity) (*httptest.Server, *Bridge, testStore) {
+	server := httptest.NewUnstartedServer(nil)
+	server.Start()
+	bridge, err := NewBridge(server.URL+"/ap", testGroupId, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := &quorumpb.Activity{
+		Type:   "Add",
+		Object: &quorumpb.Object{Type: "Note", Content: "test content", Inreplyto: &quorumpb.Reply{Trxid: "parent"}},
+		Target: &quorumpb.Object{Id: testGroupId, Type: "Group"},
+	}
+	data, err := quorumpb.ContentToBytes(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := testStore{"trx1": {TrxId: "trx1", GroupId: testGroupId, Type: quorumpb.TrxType_POST, SenderPubkey: "senderpubkey", Data: data, TimeStamp: 1658764895000000000}}
+
+	server.Config.Handler = &Handler{
+		Bridge: bridge,
+		Store:  store,
+		OnPost: func(activity *quorumpb.Activity) error {
+			*posts = append(*posts, activity)
+			return nil
+		},
+	}
+	return server, bridge, store
+}
+
+func getJSON(t *testing.T, iri string) map[string]interface{} {
+	resp, err := http.Get(iri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("get %s got status %d", iri, resp.StatusCode)
+	}
+	var doc map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestHandlerGetPost(t *testing.T) {
+	var posts []*quorumpb.Activity
+	server, bridge, store := newTestServerWithStore(t, &posts)
+	defer server.Close()
+
+	resp, err := http.Get(bridge.TrxIRI("trx1") + "/activity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != ContentType {
+		t.Fatalf("get activity got status %d, content type %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	var doc map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["type"] != "Create" || doc["actor"] != bridge.ActorIRI("senderpubkey") || doc["audience"] != bridge.GroupIRI() {
+		t.Errorf("unexpected activity %v", doc)
+	}
+	object := doc["object"].(map[string]interface{})
+	if object["id"] != bridge.TrxIRI("trx1") || object["inReplyTo"] != bridge.TrxIRI("parent") {
+		t.Errorf("unexpected object %v", object)
+	}
+
+	//ids are unescaped once
+	for _, trxid := range []string{"a/b", "a%2Fb"} {
+		store[trxid] = proto.Clone(store["trx1"]).(*quorumpb.Trx)
+		store[trxid].TrxId = trxid
+	}
+	for _, trxid := range []string{"a/b", "a%2Fb"} {
+		if doc := getJSON(t, bridge.TrxIRI(trxid)); doc["id"] != bridge.TrxIRI(trxid) {
+			t.Errorf("get post %s got %v", trxid, doc["id"])
+		}
+	}
+
+	store["other"] = proto.Clone(store["trx1"]).(*quorumpb.Trx)
+	store["other"].GroupId = "another group"
+	for _, iri := range []string{bridge.TrxIRI("unknown"), bridge.TrxIRI("other"), bridge.TrxIRI("trx1") + "/unknown"} {
+		resp, err = http.Get(iri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("get %s got status %d", iri, resp.StatusCode)
+		}
+	}
+}
+
+func TestHandlerInbox(t *testing.T) {
+	var posts []*quorumpb.Activity
+	server, bridge := newTestServer(t, &posts)
+	defer server.Close()
+
+	inbound := map[string]interface{}{
+		"@context": quorumpb.ActivityStreamsContext,
+		"type":     "Create",
+		"actor":    "https://example.com/users/alice",
+		"object": map[string]interface{}{
+			"type":      "Note",
+			"content":   "hello",
+			"inReplyTo": bridge.TrxIRI("trx1"),
+			"to":        PublicIRI,
+		},
+	}
+	body, _ := json.Marshal(inbound)
+	resp, err := http.Post(bridge.GroupIRI()+"/inbox", ContentType, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("post to inbox got status %d", resp.StatusCode)
+	}
+	if len(posts) != 1 {
+		t.Fatalf("got %d posts, expect 1", len(posts))
+	}
+	post := posts[0]
+	if post.Type != "Add" || post.Target.Id != testGroupId || post.Object.Content != "hello" {
+		t.Errorf("unexpected post %v", post)
+	}
+	if post.Object.Inreplyto.Trxid != "trx1" || post.Object.Inreplyto.Groupid != testGroupId {
+		t.Errorf("reply not mapped to trx id: %v", post.Object.Inreplyto)
+	}
+	if len(post.Object.To) != 0 {
+		t.Errorf("inbound recipients should be dropped")
+	}
+
+	body, _ = json.Marshal(map[string]interface{}{"@context": quorumpb.ActivityStreamsContext, "type": "Follow", "actor": "https://example.com/users/alice"})
+	resp, err = http.Post(bridge.GroupIRI()+"/inbox", ContentType, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("follow without OnFollow got status %d", resp.StatusCode)
+	}
+}
+
+func TestHandlerCollections(t *testing.T) {
+	var posts []*quorumpb.Activity
+	server, bridge, store := newTestServerWithStore(t, &posts)
+	defer server.Close()
+	for i := 0; i < COLLECTION_PAGE_SIZE; i++ {
+		trxid := fmt.Sprintf("trx%d", i+2)
+		store[trxid] = &quorumpb.Trx{TrxId: trxid, GroupId: testGroupId, Type: quorumpb.TrxType_POST, SenderPubkey: "senderpubkey", Data: store["trx1"].Data, TimeStamp: store["trx1"].TimeStamp + int64(i+1)}
+	}
+	var followers []string
+	for i := 0; i < 3; i++ {
+		followers = append(followers, fmt.Sprintf("https://example.com/users/%d", i))
+	}
+	server.Config.Handler.(*Handler).Followers = func() ([]string, error) { return followers, nil }
+
+	outbox := getJSON(t, bridge.GroupIRI()+"/outbox")
+	if outbox["type"] != "OrderedCollection" || outbox["totalItems"] != float64(COLLECTION_PAGE_SIZE+1) {
+		t.Fatalf("unexpected outbox %v", outbox)
+	}
+	page := getJSON(t, outbox["first"].(string))
+	items := page["orderedItems"].([]interface{})
+	if page["type"] != "OrderedCollectionPage" || len(items) != COLLECTION_PAGE_SIZE || page["next"] == nil {
+		t.Fatalf("unexpected first outbox page %v", page)
+	}
+	if first := items[0].(map[string]interface{}); first["id"] != bridge.TrxIRI(fmt.Sprintf("trx%d", COLLECTION_PAGE_SIZE+1))+"/activity" || first["type"] != "Create" {
+		t.Errorf("outbox page should start with the newest post, got %v", first)
+	}
+	page = getJSON(t, page["next"].(string))
+	items = page["orderedItems"].([]interface{})
+	if len(items) != 1 || items[0].(map[string]interface{})["id"] != bridge.TrxIRI("trx1")+"/activity" || page["next"] != nil {
+		t.Errorf("unexpected last outbox page %v", page)
+	}
+
+	collection := getJSON(t, bridge.GroupIRI()+"/followers")
+	if collection["totalItems"] != float64(len(followers)) {
+		t.Fatalf("unexpected followers %v", collection)
+	}
+	page = getJSON(t, collection["first"].(string))
+	if items := page["orderedItems"].([]interface{}); len(items) != len(followers) || items[0] != followers[0] {
+		t.Errorf("unexpected followers page %v", page)
+	}
+}
+
+func TestHandlerActorPublicKey(t *testing.T) {
+	var posts []*quorumpb.Activity
+	server, bridge := newTestServer(t, &posts)
+	defer server.Close()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Config.Handler.(*Handler).PublicKey = &key.PublicKey
+
+	actor := getJSON(t, bridge.GroupIRI())
+	publickey, ok := actor["publicKey"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("actor has no public key: %v", actor)
+	}
+	if publickey["id"] != bridge.GroupIRI()+"#main-key" || publickey["owner"] != bridge.GroupIRI() || !strings.HasPrefix(publickey["publicKeyPem"].(string), "-----BEGIN PUBLIC KEY-----") {
+		t.Errorf("unexpected public key %v", publickey)
+	}
+}
+
+func TestHandlerFollow(t *testing.T) {
+	var posts []*quorumpb.Activity
+	server, bridge := newTestServer(t, &posts)
+	defer server.Close()
+	var follows []string
+	var delivered []map[string]interface{}
+	handler := server.Config.Handler.(*Handler)
+	handler.OnFollow = func(actor string) error {
+		follows = append(follows, actor)
+		return nil
+	}
+	handler.Deliver = func(actor string, activity []byte) error {
+		var doc map[string]interface{}
+		if err := json.Unmarshal(activity, &doc); err != nil {
+			return err
+		}
+		doc["to"] = actor
+		delivered = append(delivered, doc)
+		return nil
+	}
+
+	follow := map[string]interface{}{"@context": quorumpb.ActivityStreamsContext, "id": "https://example.com/follows/1", "type": "Follow", "actor": "https://example.com/users/alice", "object": bridge.GroupIRI()}
+	body, _ := json.Marshal(follow)
+	resp, err := http.Post(bridge.GroupIRI()+"/inbox", ContentType, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("follow got status %d", resp.StatusCode)
+	}
+	if len(follows) != 1 || len(delivered) != 1 {
+		t.Fatalf("got %d follows and %d delivered activities, expect 1", len(follows), len(delivered))
+	}
+	accept := delivered[0]
+	if accept["type"] != "Accept" || accept["actor"] != bridge.GroupIRI() || accept["to"] != "https://example.com/users/alice" || accept["id"] == nil {
+		t.Errorf("unexpected accept %v", accept)
+	}
+	if object := accept["object"].(map[string]interface{}); object["id"] != follow["id"] || object["type"] != "Follow" {
+		t.Errorf("accept object should be the follow, got %v", object)
+	}
+}
+
+func TestTrxToActivityReply(t *testing.T) {
+	bridge, err := NewBridge("https://example.com/ap", testGroupId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bridge.GroupBaseURLs = map[string]string{"othergroup": "https://other.example.com/ap/"}
+
+	for _, test := range []struct {
+		reply  *quorumpb.Reply
+		expect string
+	}{
+		{&quorumpb.Reply{Trxid: "parent"}, bridge.TrxIRI("parent")},
+		{&quorumpb.Reply{Trxid: "parent", Groupid: testGroupId}, bridge.TrxIRI("parent")},
+		{&quorumpb.Reply{Trxid: "parent", Groupid: "othergroup"}, "https://other.example.com/ap/trxs/parent"},
+		{&quorumpb.Reply{Trxid: "parent", Groupid: "unknowngroup"}, ""},
+		{&quorumpb.Reply{Trxid: "https://example.org/notes/1", Groupid: "unknowngroup"}, "https://example.org/notes/1"},
+	} {
+		content := &quorumpb.Object{Type: "Note", Content: "reply", Inreplyto: test.reply}
+		trxdata, err := quorumpb.ContentToBytes(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trx := &quorumpb.Trx{TrxId: "trx1", GroupId: testGroupId, Type: quorumpb.TrxType_POST, SenderPubkey: "senderpubkey"}
+		activity, err := bridge.TrxToActivity(trx, trxdata)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := activity.Object.Inreplyto.GetTrxid(); got != test.expect {
+			t.Errorf("reply %v got inReplyTo %q, expect %q", test.reply, got, test.expect)
+		}
+	}
+}
+
+func TestTrxToActivityCompressed(t *testing.T) {
+	bridge, err := NewBridge("https://example.com/ap", testGroupId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := bytes.Repeat([]byte("image content "), 64)
+	content, err := data.CompressContent(&quorumpb.Object{Type: "Note", Content: "image", Image: []*quorumpb.Image{{Compression: quorumpb.File_gz, Content: image}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trxdata, err := quorumpb.ContentToBytes(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trx := &quorumpb.Trx{TrxId: "trx1", GroupId: testGroupId, Type: quorumpb.TrxType_POST, SenderPubkey: "senderpubkey"}
+	activity, err := bridge.TrxToActivity(trx, trxdata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activity.Object.Image) != 1 || !bytes.Equal(activity.Object.Image[0].Content, image) {
+		t.Errorf("image content not decompressed")
+	}
+}
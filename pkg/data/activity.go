@@ -0,0 +1,114 @@
+package data
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// ActivityVerb lists the fields an Activity of a verb must, or must not, carry
+type ActivityVerb struct {
+	ObjectRequired     bool //Activity.Object must be set
+	ObjectIdRequired   bool //Activity.Object.Id must be set
+	ObjectTypeRequired bool //Activity.Object.Type must be set
+	TargetRequired     bool //Activity.Target.Id must be set
+	ObjectMustExist    bool //Activity.Object.Id must be known by the ObjectLookup
+	NoObjectContent    bool //Activity.Object must carry only its id and type
+}
+
+// ObjectLookup tells whether an object id refers to an existing post
+type ObjectLookup interface {
+	ObjectExists(id string) bool
+}
+
+// ActivityValidator checks that the verb of an Activity is supported and that its object makes sense for it
+type ActivityValidator struct {
+	mu     sync.RWMutex
+	verbs  map[string]ActivityVerb
+	lookup ObjectLookup
+}
+
+// DefaultActivityVerbs returns the verbs supported by a new ActivityValidator
+func DefaultActivityVerbs() map[string]ActivityVerb {
+	return map[string]ActivityVerb{
+		"Create":   {ObjectRequired: true, ObjectTypeRequired: true},
+		"Add":      {ObjectRequired: true, ObjectTypeRequired: true, TargetRequired: true},
+		"Update":   {ObjectRequired: true, ObjectIdRequired: true, ObjectMustExist: true},
+		"Delete":   {ObjectRequired: true, ObjectIdRequired: true, ObjectMustExist: true, NoObjectContent: true},
+		"Like":     {ObjectRequired: true, ObjectIdRequired: true, ObjectMustExist: true},
+		"Dislike":  {ObjectRequired: true, ObjectIdRequired: true, ObjectMustExist: true},
+		"Follow":   {ObjectRequired: true, ObjectIdRequired: true},
+		"Announce": {ObjectRequired: true, ObjectIdRequired: true, ObjectMustExist: true},
+	}
+}
+
+// NewActivityValidator creates a validator of the default verbs, lookup can be nil to skip the existence checks
+func NewActivityValidator(lookup ObjectLookup) *ActivityValidator {
+	return &ActivityValidator{verbs: DefaultActivityVerbs(), lookup: lookup}
+}
+
+// Register adds or replaces a supported verb
+func (validator *ActivityValidator) Register(verbtype string, verb ActivityVerb) {
+	validator.mu.Lock()
+	defer validator.mu.Unlock()
+	validator.verbs[verbtype] = verb
+}
+
+// Verbs returns the supported verbs, sorted
+func (validator *ActivityValidator) Verbs() []string {
+	validator.mu.RLock()
+	defer validator.mu.RUnlock()
+	verbs := make([]string, 0, len(validator.verbs))
+	for v := range validator.verbs {
+		verbs = append(verbs, v)
+	}
+	sort.Strings(verbs)
+	return verbs
+}
+
+// Validate checks an Activity content, other content types are not checked
+func (validator *ActivityValidator) Validate(content proto.Message) error {
+	activity, ok := content.(*quorumpb.Activity)
+	if !ok {
+		return nil
+	}
+
+	validator.mu.RLock()
+	verb, ok := validator.verbs[activity.Type]
+	validator.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unsupported activity type %q", activity.Type)
+	}
+
+	//a profile update has a person instead of an object
+	if isProfileUpdate(activity) {
+		return ValidatePerson(activity.Person)
+	}
+
+	obj := activity.Object
+	if obj == nil {
+		if verb.ObjectRequired {
+			return fmt.Errorf("%s activity must have an object", activity.Type)
+		}
+		return nil
+	}
+	if verb.ObjectIdRequired && obj.Id == "" {
+		return fmt.Errorf("%s activity must have an object id", activity.Type)
+	}
+	if verb.ObjectTypeRequired && obj.Type == "" {
+		return fmt.Errorf("%s activity must have an object type", activity.Type)
+	}
+	if verb.TargetRequired && (activity.Target == nil || activity.Target.Id == "") {
+		return fmt.Errorf("%s activity must have a target", activity.Type)
+	}
+	if verb.NoObjectContent && !proto.Equal(obj, &quorumpb.Object{Id: obj.Id, Type: obj.Type}) {
+		return fmt.Errorf("%s activity object must carry no content", activity.Type)
+	}
+	if verb.ObjectMustExist && validator.lookup != nil && !validator.lookup.ObjectExists(obj.Id) {
+		return fmt.Errorf("%s activity refers to unknown object %s", activity.Type, obj.Id)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package data
+
+import (
+	"testing"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+)
+
+type testObjectLookup map[string]bool
+
+func (lookup testObjectLookup) ObjectExists(id string) bool {
+	return lookup[id]
+}
+
+func TestActivityValidator(t *testing.T) {
+	validator := NewActivityValidator(testObjectLookup{"0001": true})
+	group := &quorumpb.Object{Id: GetGroupItem().GroupId, Type: "Group"}
+
+	valids := []*quorumpb.Activity{
+		{Type: "Add", Object: &quorumpb.Object{Type: "Note", Content: "test content", Id: "0002"}, Target: group},
+		{Type: "Create", Object: &quorumpb.Object{Type: "Note", Content: "test content"}},
+		{Type: "Update", Object: &quorumpb.Object{Type: "Note", Content: "new content", Id: "0001"}},
+		{Type: "Delete", Object: &quorumpb.Object{Type: "Note", Id: "0001"}},
+		{Type: "Like", Object: &quorumpb.Object{Id: "0001"}},
+		{Type: "Follow", Object: &quorumpb.Object{Id: "https://example.com/users/alice"}},
+	}
+	for _, activity := range valids {
+		if err := validator.Validate(activity); err != nil {
+			t.Errorf("valid %s activity rejected: %s", activity.Type, err)
+		}
+	}
+
+	invalids := []*quorumpb.Activity{
+		{Type: "Burn", Object: &quorumpb.Object{Id: "0001"}},
+		{Type: "Add", Object: &quorumpb.Object{Type: "Note", Content: "test content"}},
+		{Type: "Create", Object: &quorumpb.Object{Content: "test content"}},
+		{Type: "Update", Object: &quorumpb.Object{Type: "Note", Content: "new content", Id: "0009"}},
+		{Type: "Delete", Object: &quorumpb.Object{Type: "Note", Id: "0001", Content: "test content"}},
+		{Type: "Like", Object: &quorumpb.Object{Type: "Note"}},
+		{Type: "Dislike"},
+	}
+	for _, activity := range invalids {
+		if err := validator.Validate(activity); err == nil {
+			t.Errorf("invalid %s activity accepted", activity.Type)
+		}
+	}
+
+	if err := validator.Validate(&quorumpb.Object{Type: "Note"}); err != nil {
+		t.Errorf("non activity content rejected: %s", err)
+	}
+
+	validator.Register("Burn", ActivityVerb{ObjectRequired: true, ObjectIdRequired: true})
+	if err := validator.Validate(invalids[0]); err != nil {
+		t.Errorf("registered verb rejected: %s", err)
+	}
+}
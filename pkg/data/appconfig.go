@@ -0,0 +1,182 @@
+package data
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// AppConfigState folds the APP_CONFIG items of a group into the current config set
+type AppConfigState struct {
+	groupId string
+	mu      sync.RWMutex
+	items   map[string]*quorumpb.AppConfigItem
+}
+
+func NewAppConfigState(groupId string) *AppConfigState {
+	return &AppConfigState{groupId: groupId, items: make(map[string]*quorumpb.AppConfigItem)}
+}
+
+// Apply adds or removes a config item, items should be applied in block order.
+// An ADD item whose value does not match its type is rejected.
+func (state *AppConfigState) Apply(item *quorumpb.AppConfigItem) error {
+	if item.GroupId != state.groupId {
+		return fmt.Errorf("app config item for group %s, expect group %s", item.GroupId, state.groupId)
+	}
+	if item.Name == "" {
+		return fmt.Errorf("app config item name must not be empty")
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	switch item.Action {
+	case quorumpb.ActionType_ADD:
+		if err := validateAppConfigValue(item); err != nil {
+			return err
+		}
+		state.items[item.Name] = proto.Clone(item).(*quorumpb.AppConfigItem)
+	case quorumpb.ActionType_REMOVE:
+		delete(state.items, item.Name)
+	default:
+		return fmt.Errorf("unknown app config item action %s", item.Action)
+	}
+	return nil
+}
+
+// ApplyTrxData applies the (decrypted) data of an APP_CONFIG trx
+func (state *AppConfigState) ApplyTrxData(data []byte) error {
+	item := &quorumpb.AppConfigItem{}
+	if err := proto.Unmarshal(data, item); err != nil {
+		return err
+	}
+	return state.Apply(item)
+}
+
+// Names returns the names of all config items, sorted
+func (state *AppConfigState) Names() []string {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	names := make([]string, 0, len(state.items))
+	for name := range state.items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetItem returns a copy of the config item with the given name
+func (state *AppConfigState) GetItem(name string) (*quorumpb.AppConfigItem, bool) {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	item, ok := state.items[name]
+	if !ok {
+		return nil, false
+	}
+	return proto.Clone(item).(*quorumpb.AppConfigItem), true
+}
+
+func (state *AppConfigState) GetInt(name string) (int64, error) {
+	value, err := state.getValue(name, quorumpb.AppConfigType_INT)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func (state *AppConfigState) GetBool(name string) (bool, error) {
+	value, err := state.getValue(name, quorumpb.AppConfigType_BOOL)
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(value)
+}
+
+func (state *AppConfigState) GetString(name string) (string, error) {
+	return state.getValue(name, quorumpb.AppConfigType_STRING)
+}
+
+func (state *AppConfigState) getValue(name string, configtype quorumpb.AppConfigType) (string, error) {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	item, ok := state.items[name]
+	if !ok {
+		return "", fmt.Errorf("app config %s not found", name)
+	}
+	if item.Type != configtype {
+		return "", fmt.Errorf("app config %s is %s, not %s", name, item.Type, configtype)
+	}
+	return item.Value, nil
+}
+
+// Snapshot exports the current config items as SNAPSHOT_APP_CONFIG snapshot items, sorted by name
+func (state *AppConfigState) Snapshot() ([]*quorumpb.SnapshotItem, error) {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	names := make([]string, 0, len(state.items))
+	for name := range state.items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var snapshotItems []*quorumpb.SnapshotItem
+	for _, name := range names {
+		data, err := proto.Marshal(state.items[name])
+		if err != nil {
+			return nil, err
+		}
+		snapshotItems = append(snapshotItems, &quorumpb.SnapshotItem{
+			SnapshotItemId: name,
+			Type:           quorumpb.SnapShotItemType_SNAPSHOT_APP_CONFIG,
+			Data:           data,
+		})
+	}
+	return snapshotItems, nil
+}
+
+// LoadSnapshot replaces the current config items with the SNAPSHOT_APP_CONFIG items of a snapshot,
+// snapshot items of other types are ignored
+func (state *AppConfigState) LoadSnapshot(snapshotItems []*quorumpb.SnapshotItem) error {
+	items := make(map[string]*quorumpb.AppConfigItem)
+	for _, snapshotItem := range snapshotItems {
+		if snapshotItem.Type != quorumpb.SnapShotItemType_SNAPSHOT_APP_CONFIG {
+			continue
+		}
+		item := &quorumpb.AppConfigItem{}
+		if err := proto.Unmarshal(snapshotItem.Data, item); err != nil {
+			return err
+		}
+		if item.GroupId != state.groupId {
+			return fmt.Errorf("app config item for group %s, expect group %s", item.GroupId, state.groupId)
+		}
+		if err := validateAppConfigValue(item); err != nil {
+			return err
+		}
+		items[item.Name] = item
+	}
+
+	state.mu.Lock()
+	state.items = items
+	state.mu.Unlock()
+	return nil
+}
+
+func validateAppConfigValue(item *quorumpb.AppConfigItem) error {
+	var err error
+	switch item.Type {
+	case quorumpb.AppConfigType_INT:
+		_, err = strconv.ParseInt(item.Value, 10, 64)
+	case quorumpb.AppConfigType_BOOL:
+		_, err = strconv.ParseBool(item.Value)
+	case quorumpb.AppConfigType_STRING:
+	default:
+		return fmt.Errorf("unknown app config type %s of %s", item.Type, item.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("app config %s value %q is not a valid %s", item.Name, item.Value, item.Type)
+	}
+	return nil
+}
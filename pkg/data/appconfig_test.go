@@ -0,0 +1,61 @@
+package data
+
+import (
+	"testing"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+)
+
+func TestAppConfigState(t *testing.T) {
+	groupId := GetGroupItem().GroupId
+	state := NewAppConfigState(groupId)
+
+	items := []*quorumpb.AppConfigItem{
+		{GroupId: groupId, Action: quorumpb.ActionType_ADD, Name: "max_post", Type: quorumpb.AppConfigType_INT, Value: "10"},
+		{GroupId: groupId, Action: quorumpb.ActionType_ADD, Name: "enable_like", Type: quorumpb.AppConfigType_BOOL, Value: "true"},
+		{GroupId: groupId, Action: quorumpb.ActionType_ADD, Name: "title", Type: quorumpb.AppConfigType_STRING, Value: "hello"},
+		{GroupId: groupId, Action: quorumpb.ActionType_ADD, Name: "max_post", Type: quorumpb.AppConfigType_INT, Value: "20"},
+		{GroupId: groupId, Action: quorumpb.ActionType_REMOVE, Name: "title"},
+	}
+	for _, item := range items {
+		if err := state.Apply(item); err != nil {
+			t.Fatalf("apply app config %s err: %s", item.Name, err)
+		}
+	}
+
+	if v, err := state.GetInt("max_post"); err != nil || v != 20 {
+		t.Errorf("GetInt got %d, %v", v, err)
+	}
+	if v, err := state.GetBool("enable_like"); err != nil || v != true {
+		t.Errorf("GetBool got %v, %v", v, err)
+	}
+	if _, err := state.GetString("title"); err == nil {
+		t.Errorf("removed config should not be found")
+	}
+	if _, err := state.GetString("max_post"); err == nil {
+		t.Errorf("GetString on INT config should fail")
+	}
+
+	invalid := &quorumpb.AppConfigItem{GroupId: groupId, Action: quorumpb.ActionType_ADD, Name: "enable_like", Type: quorumpb.AppConfigType_BOOL, Value: "maybe"}
+	if err := state.Apply(invalid); err == nil {
+		t.Errorf("invalid bool value accepted")
+	}
+	if v, _ := state.GetBool("enable_like"); v != true {
+		t.Errorf("invalid item should not change state")
+	}
+
+	snapshotItems, err := state.Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot err: %s", err)
+	}
+	restored := NewAppConfigState(groupId)
+	if err := restored.LoadSnapshot(snapshotItems); err != nil {
+		t.Fatalf("load snapshot err: %s", err)
+	}
+	if len(restored.Names()) != 2 {
+		t.Errorf("restored state has %d items, expect 2", len(restored.Names()))
+	}
+	if v, err := restored.GetInt("max_post"); err != nil || v != 20 {
+		t.Errorf("restored GetInt got %d, %v", v, err)
+	}
+}
@@ -0,0 +1,275 @@
+package data
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// A chain archive holds a group for backup and migration, all integers are big endian:
+//
+//	magic "RUMCHAIN" | version uint8
+//	record* : kind uint8 | length uint32 | protobuf encoded payload
+//	end     : ARCHIVE_RECORD_END | length uint32 (32) | sha256 of all the bytes before the end record
+//
+// The first record is the GroupSeed, followed by the blocks after the genesis block in height order,
+// optionally followed by one Snapshot.
+const (
+	ARCHIVE_MAGIC   = "RUMCHAIN"
+	ARCHIVE_VERSION = 1
+
+	ARCHIVE_RECORD_SEED     = 1
+	ARCHIVE_RECORD_BLOCK    = 2
+	ARCHIVE_RECORD_SNAPSHOT = 3
+	ARCHIVE_RECORD_END      = 0xff
+
+	ARCHIVE_RECORD_SIZE_LIMIT = 64 * 1024 * 1024 //(64Mb)
+)
+
+// ArchiveWriter writes a chain archive, Close must be called to write the checksum
+type ArchiveWriter struct {
+	w        io.Writer
+	checksum hash.Hash
+	groupId  string
+	last     *quorumpb.Block
+	snapshot bool
+	closed   bool
+}
+
+// NewArchiveWriter writes the archive header with the seed of the group
+func NewArchiveWriter(w io.Writer, seed *quorumpb.GroupSeed) (*ArchiveWriter, error) {
+	if seed.GenesisBlock == nil {
+		return nil, fmt.Errorf("seed of group %s has no genesis block", seed.GroupId)
+	}
+	writer := &ArchiveWriter{w: w, checksum: sha256.New(), groupId: seed.GroupId, last: seed.GenesisBlock}
+	if err := writer.write(append([]byte(ARCHIVE_MAGIC), ARCHIVE_VERSION)); err != nil {
+		return nil, err
+	}
+	if err := writer.writeRecord(ARCHIVE_RECORD_SEED, seed); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// WriteBlock appends the block following the last written one
+func (writer *ArchiveWriter) WriteBlock(block *quorumpb.Block) error {
+	if writer.closed || writer.snapshot {
+		return fmt.Errorf("can not write block %s after the snapshot or the end of the archive", block.BlockId)
+	}
+	if block.GroupId != writer.groupId {
+		return fmt.Errorf("block %s of group %s, expect group %s", block.BlockId, block.GroupId, writer.groupId)
+	}
+	if block.PrevBlockId != writer.last.BlockId {
+		return fmt.Errorf("block %s does not follow block %s", block.BlockId, writer.last.BlockId)
+	}
+	if err := writer.writeRecord(ARCHIVE_RECORD_BLOCK, block); err != nil {
+		return err
+	}
+	writer.last = block
+	return nil
+}
+
+// WriteSnapshot appends the snapshot of the group, no block can be written after it
+func (writer *ArchiveWriter) WriteSnapshot(snapshot *quorumpb.Snapshot) error {
+	if writer.closed || writer.snapshot {
+		return fmt.Errorf("can not write the snapshot twice or after the end of the archive")
+	}
+	if snapshot.GroupId != writer.groupId {
+		return fmt.Errorf("snapshot of group %s, expect group %s", snapshot.GroupId, writer.groupId)
+	}
+	if err := writer.writeRecord(ARCHIVE_RECORD_SNAPSHOT, snapshot); err != nil {
+		return err
+	}
+	writer.snapshot = true
+	return nil
+}
+
+// Close writes the trailing checksum, it does not close the underlying writer
+func (writer *ArchiveWriter) Close() error {
+	if writer.closed {
+		return nil
+	}
+	writer.closed = true
+	sum := writer.checksum.Sum(nil)
+	return writer.writeBytesRecord(ARCHIVE_RECORD_END, sum)
+}
+
+func (writer *ArchiveWriter) writeRecord(kind byte, msg proto.Message) error {
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return writer.writeBytesRecord(kind, payload)
+}
+
+func (writer *ArchiveWriter) writeBytesRecord(kind byte, payload []byte) error {
+	if len(payload) > ARCHIVE_RECORD_SIZE_LIMIT {
+		return fmt.Errorf("%w: archive record size %d over %d", ErrOversize, len(payload), ARCHIVE_RECORD_SIZE_LIMIT)
+	}
+	header := make([]byte, 5)
+	header[0] = kind
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if err := writer.write(header); err != nil {
+		return err
+	}
+	return writer.write(payload)
+}
+
+func (writer *ArchiveWriter) write(b []byte) error {
+	writer.checksum.Write(b)
+	_, err := writer.w.Write(b)
+	return err
+}
+
+// ArchiveReader reads a chain archive and validates it as it streams: the genesis block of the seed,
+// then every block with IsBlockValid against the previous one. The archive is complete and its checksum
+// verified only when Next returns io.EOF.
+type ArchiveReader struct {
+	r        io.Reader
+	checksum hash.Hash
+	seed     *quorumpb.GroupSeed
+	last     *quorumpb.Block
+	snapshot *quorumpb.Snapshot
+	done     bool
+}
+
+// NewArchiveReader reads the archive header and validates the group seed
+func NewArchiveReader(r io.Reader) (*ArchiveReader, error) {
+	reader := &ArchiveReader{r: r, checksum: sha256.New()}
+	header := make([]byte, len(ARCHIVE_MAGIC)+1)
+	if err := reader.read(header); err != nil {
+		return nil, fmt.Errorf("read archive header failed: %s", err)
+	}
+	if string(header[:len(ARCHIVE_MAGIC)]) != ARCHIVE_MAGIC {
+		return nil, fmt.Errorf("not a chain archive")
+	}
+	if version := header[len(ARCHIVE_MAGIC)]; version != ARCHIVE_VERSION {
+		return nil, fmt.Errorf("unsupported archive version %d", version)
+	}
+
+	kind, payload, err := reader.readRecord()
+	if err != nil {
+		return nil, err
+	}
+	if kind != ARCHIVE_RECORD_SEED {
+		return nil, fmt.Errorf("archive must start with the group seed, got record %d", kind)
+	}
+	seed := &quorumpb.GroupSeed{}
+	if err := proto.Unmarshal(payload, seed); err != nil {
+		return nil, fmt.Errorf("invalid group seed: %s", err)
+	}
+	if ok, err := VerifyGroupSeed(seed); !ok || err != nil {
+		if err == nil {
+			err = fmt.Errorf("invalid group seed of group %s", seed.GroupId)
+		}
+		return nil, err
+	}
+	reader.seed = seed
+	reader.last = seed.GenesisBlock
+	return reader, nil
+}
+
+// Seed returns the group seed of the archive
+func (reader *ArchiveReader) Seed() *quorumpb.GroupSeed {
+	return reader.seed
+}
+
+// Snapshot returns the snapshot of the archive, if any, once Next returned io.EOF
+func (reader *ArchiveReader) Snapshot() *quorumpb.Snapshot {
+	if !reader.done {
+		return nil
+	}
+	return reader.snapshot
+}
+
+// Next returns the next validated block, or io.EOF once the snapshot and the checksum are read and verified
+func (reader *ArchiveReader) Next() (*quorumpb.Block, error) {
+	if reader.done {
+		return nil, io.EOF
+	}
+	for {
+		//the checksum covers everything before the end record
+		sum := reader.checksum.Sum(nil)
+		kind, payload, err := reader.readRecord()
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case ARCHIVE_RECORD_BLOCK:
+			if reader.snapshot != nil {
+				return nil, fmt.Errorf("block after the snapshot")
+			}
+			block := &quorumpb.Block{}
+			if err := proto.Unmarshal(payload, block); err != nil {
+				return nil, fmt.Errorf("invalid block after block %s: %s", reader.last.BlockId, err)
+			}
+			if block.GroupId != reader.seed.GroupId {
+				return nil, fmt.Errorf("block %s of group %s, expect group %s", block.BlockId, block.GroupId, reader.seed.GroupId)
+			}
+			if ok, err := IsBlockValid(block, reader.last); !ok || err != nil {
+				if err == nil {
+					err = fmt.Errorf("invalid block %s after block %s", block.BlockId, reader.last.BlockId)
+				}
+				return nil, err
+			}
+			reader.last = block
+			return block, nil
+		case ARCHIVE_RECORD_SNAPSHOT:
+			if reader.snapshot != nil {
+				return nil, fmt.Errorf("more than one snapshot")
+			}
+			snapshot := &quorumpb.Snapshot{}
+			if err := proto.Unmarshal(payload, snapshot); err != nil {
+				return nil, fmt.Errorf("invalid snapshot: %s", err)
+			}
+			if snapshot.GroupId != reader.seed.GroupId {
+				return nil, fmt.Errorf("snapshot of group %s, expect group %s", snapshot.GroupId, reader.seed.GroupId)
+			}
+			reader.snapshot = snapshot
+		case ARCHIVE_RECORD_END:
+			if !bytes.Equal(payload, sum) {
+				return nil, fmt.Errorf("archive checksum mismatch")
+			}
+			reader.done = true
+			return nil, io.EOF
+		default:
+			return nil, fmt.Errorf("unknown archive record %d", kind)
+		}
+	}
+}
+
+func (reader *ArchiveReader) readRecord() (byte, []byte, error) {
+	header := make([]byte, 5)
+	if err := reader.read(header); err != nil {
+		if err == io.EOF {
+			return 0, nil, fmt.Errorf("archive truncated, no checksum")
+		}
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > ARCHIVE_RECORD_SIZE_LIMIT {
+		return 0, nil, fmt.Errorf("%w: archive record size %d over %d", ErrOversize, length, ARCHIVE_RECORD_SIZE_LIMIT)
+	}
+	payload := make([]byte, length)
+	if err := reader.read(payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, fmt.Errorf("read archive record failed: %s", err)
+	}
+	return header[0], payload, nil
+}
+
+func (reader *ArchiveReader) read(b []byte) error {
+	if _, err := io.ReadFull(reader.r, b); err != nil {
+		return err
+	}
+	reader.checksum.Write(b)
+	return nil
+}
@@ -0,0 +1,182 @@
+package data
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+
+	localcrypto "github.com/rumsystem/keystore/pkg/crypto"
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+func getTestChain(t testing.TB, count int) (*quorumpb.GroupSeed, []*quorumpb.Block) {
+	keystoreDir := t.TempDir()
+	groupitem := GetGroupItem()
+	_, pubkey, err := GetKeyStorePubKey(groupitem.GroupId, keystoreDir)
+	if err != nil {
+		t.Fatalf("keystore new key err : %s", err)
+	}
+	ks := localcrypto.GetKeystore()
+	genesis, err := CreateGenesisBlockByEthKey(groupitem.GroupId, pubkey, ks, "")
+	if err != nil {
+		t.Fatalf("create genesis block err: %s", err)
+	}
+	seed := &quorumpb.GroupSeed{GenesisBlock: genesis, GroupId: groupitem.GroupId, GroupName: groupitem.GroupName, OwnerPubkey: pubkey}
+	if err := SignGroupSeed(seed, ks, ""); err != nil {
+		t.Fatalf("sign group seed err: %s", err)
+	}
+
+	var blocks []*quorumpb.Block
+	prev := genesis
+	for i := 0; i < count; i++ {
+		block, err := CreateBlockByEthKey(prev, nil, pubkey, ks, "")
+		if err != nil {
+			t.Fatalf("create block err: %s", err)
+		}
+		blocks = append(blocks, block)
+		prev = block
+	}
+	return seed, blocks
+}
+
+func writeTestArchive(t *testing.T, seed *quorumpb.GroupSeed, blocks []*quorumpb.Block, snapshot *quorumpb.Snapshot) []byte {
+	var buf bytes.Buffer
+	writer, err := NewArchiveWriter(&buf, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, block := range blocks {
+		if err := writer.WriteBlock(block); err != nil {
+			t.Fatalf("write block err: %s", err)
+		}
+	}
+	if snapshot != nil {
+		if err := writer.WriteSnapshot(snapshot); err != nil {
+			t.Fatalf("write snapshot err: %s", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readTestArchive(archive []byte) (*ArchiveReader, []*quorumpb.Block, error) {
+	reader, err := NewArchiveReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, nil, err
+	}
+	var blocks []*quorumpb.Block
+	for {
+		block, err := reader.Next()
+		if err == io.EOF {
+			return reader, blocks, nil
+		}
+		if err != nil {
+			return reader, blocks, err
+		}
+		blocks = append(blocks, block)
+	}
+}
+
+func TestChainArchive(t *testing.T) {
+	seed, blocks := getTestChain(t, 3)
+	snapshot := &quorumpb.Snapshot{SnapshotId: "snapshot", GroupId: seed.GroupId, HighestBlockId: blocks[2].BlockId}
+	archive := writeTestArchive(t, seed, blocks, snapshot)
+
+	reader, read, err := readTestArchive(archive)
+	if err != nil {
+		t.Fatalf("read archive err: %s", err)
+	}
+	if reader.Seed().GroupId != seed.GroupId || len(read) != 3 || read[2].BlockId != blocks[2].BlockId {
+		t.Errorf("unexpected archive content")
+	}
+	if reader.Snapshot() == nil || reader.Snapshot().SnapshotId != "snapshot" {
+		t.Errorf("archive snapshot not read")
+	}
+
+	//blocks must be written in height order
+	var buf bytes.Buffer
+	writer, _ := NewArchiveWriter(&buf, seed)
+	if err := writer.WriteBlock(blocks[1]); err == nil {
+		t.Errorf("block out of order written")
+	}
+
+	//a tampered block is detected while streaming
+	tampered := writeTestArchive(t, seed, []*quorumpb.Block{blocks[0], {BlockId: "fake", GroupId: seed.GroupId, PrevBlockId: blocks[0].BlockId, PreviousHash: blocks[0].Hash}}, nil)
+	if _, read, err := readTestArchive(tampered); err == nil || len(read) != 1 {
+		t.Errorf("tampered block accepted")
+	}
+
+	corrupted := append([]byte{}, archive...)
+	corrupted[len(corrupted)-1] ^= 0xff
+	if _, _, err := readTestArchive(corrupted); err == nil {
+		t.Errorf("bad checksum accepted")
+	}
+	if _, _, err := readTestArchive(archive[:len(archive)-40]); err == nil {
+		t.Errorf("truncated archive accepted")
+	}
+}
+
+func TestChainArchiveForgedSeed(t *testing.T) {
+	seed, blocks := getTestChain(t, 1)
+	if _, _, err := readTestArchive(writeTestArchive(t, seed, blocks, nil)); err != nil {
+		t.Fatalf("read archive err: %s", err)
+	}
+
+	ks := localcrypto.GetKeystore()
+	otherkeyname := seed.GroupId + "_other"
+	if _, err := ks.NewKeyWithDefaultPassword(otherkeyname, localcrypto.Sign); err != nil {
+		t.Fatal(err)
+	}
+	otherpubkey, err := ks.GetEncodedPubkey(otherkeyname, localcrypto.Sign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signWithOther := func(forged *quorumpb.GroupSeed) {
+		hash, err := GroupSeedHash(forged)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature, err := ks.EthSignByKeyName(otherkeyname, hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		forged.Signature = hex.EncodeToString(signature)
+	}
+	forgedGenesis, err := CreateGenesisBlockByEthKey(otherkeyname, otherpubkey, ks, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name   string
+		forge  func(forged *quorumpb.GroupSeed)
+		expect error
+	}{
+		{"unsigned seed", func(forged *quorumpb.GroupSeed) { forged.Signature = "" }, ErrBadSignature},
+		{"seed signature not hex", func(forged *quorumpb.GroupSeed) { forged.Signature = "not hex" }, ErrBadSignature},
+		{"seed modified after signing", func(forged *quorumpb.GroupSeed) { forged.CipherKey = "forged cipher key" }, ErrBadSignature},
+		{"seed signed by another key", func(forged *quorumpb.GroupSeed) { signWithOther(forged) }, ErrBadSignature},
+		{"owner replaced and seed signed again by the new owner", func(forged *quorumpb.GroupSeed) {
+			forged.OwnerPubkey = otherpubkey
+			signWithOther(forged)
+		}, ErrIneligibleProducer},
+		{"genesis block produced by another key", func(forged *quorumpb.GroupSeed) {
+			forgedGenesis.GroupId = forged.GroupId
+			forgedGenesis.Hash, _ = BlockHash(forgedGenesis)
+			forgedGenesis.Signature, _ = ks.EthSignByKeyName(otherkeyname, forgedGenesis.Hash)
+			forged.GenesisBlock = forgedGenesis
+		}, ErrIneligibleProducer},
+	} {
+		forged := proto.Clone(seed).(*quorumpb.GroupSeed)
+		test.forge(forged)
+		_, _, err := readTestArchive(writeTestArchive(t, forged, nil, nil))
+		if !errors.Is(err, test.expect) {
+			t.Errorf("%s: got err %v, expect %s", test.name, err, test.expect)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+)
+
+// ErrVerifySkipped is the result of an item not verified because the batch stopped early
+var ErrVerifySkipped = errors.New("verification skipped")
+
+// BatchVerifyOptions controls VerifyTrxs and VerifyBlocks
+type BatchVerifyOptions struct {
+	Workers       int  //number of concurrent verifications, runtime.NumCPU() if 0
+	StopOnFailure bool //stop at the first invalid item, the items not verified yet are skipped
+	WithTrxs      bool //VerifyBlocks also verifies the trxs of every block
+}
+
+// VerifyResult is the result of the verification of one item of a batch
+type VerifyResult struct {
+	Index int //index of the item in the batch
+	Valid bool
+	Err   error //why the item is invalid, or ErrVerifySkipped
+}
+
+// VerifyTrxs verifies the signatures of trxs on a bounded worker pool. It returns a result per trx,
+// and an error when ctx is done or, with StopOnFailure, for the first invalid trx.
+func VerifyTrxs(ctx context.Context, trxs []*quorumpb.Trx, opts *BatchVerifyOptions) ([]VerifyResult, error) {
+	return verifyBatch(ctx, len(trxs), opts, func(i int) error {
+		return verifyTrxSign(trxs[i])
+	})
+}
+
+// VerifyBlocks verifies the hash and the signature of blocks on a bounded worker pool, and the
+// trxs of the blocks with WithTrxs. The order of the blocks is not checked, see IsBlockValid.
+func VerifyBlocks(ctx context.Context, blocks []*quorumpb.Block, opts *BatchVerifyOptions) ([]VerifyResult, error) {
+	withtrxs := opts != nil && opts.WithTrxs
+	return verifyBatch(ctx, len(blocks), opts, func(i int) error {
+		return verifyBlock(blocks[i], withtrxs)
+	})
+}
+
+func verifyTrxSign(trx *quorumpb.Trx) error {
+	_, err := VerifyTrx(trx)
+	return err
+}
+
+func verifyBlock(block *quorumpb.Block, withtrxs bool) error {
+	hash, err := BlockHash(block)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(hash, block.Hash) {
+		return blockVerifyError(block.BlockId, ErrHashMismatch)
+	}
+	if _, err := VerifyBlockSign(block); err != nil {
+		return err
+	}
+	if withtrxs {
+		for _, trx := range block.Trxs {
+			if err := verifyTrxSign(trx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func verifyBatch(ctx context.Context, count int, opts *BatchVerifyOptions, verify func(i int) error) ([]VerifyResult, error) {
+	if opts == nil {
+		opts = &BatchVerifyOptions{}
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > count {
+		workers = count
+	}
+
+	results := make([]VerifyResult, count)
+	for i := range results {
+		results[i] = VerifyResult{Index: i, Err: ErrVerifySkipped}
+	}
+
+	batchctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var failure error
+	var failureonce sync.Once
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				err := verify(i)
+				results[i] = VerifyResult{Index: i, Valid: err == nil, Err: err}
+				if err != nil && opts.StopOnFailure {
+					failureonce.Do(func() {
+						failure = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < count; i++ {
+		if batchctx.Err() != nil {
+			break
+		}
+		select {
+		case jobs <- i:
+		case <-batchctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if failure != nil {
+		return results, failure
+	}
+	return results, ctx.Err()
+}
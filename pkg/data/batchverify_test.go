@@ -0,0 +1,126 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+)
+
+func getTestTrxs(t testing.TB, count int) []*quorumpb.Trx {
+	keystoreDir := t.TempDir()
+	trxFactory := &TrxFactory{}
+	groupitem := GetGroupItem()
+	trxFactory.Init("1.0.0", groupitem, "default", &TestNonce{})
+	_, pubkey, err := GetKeyStorePubKey(groupitem.GroupId, keystoreDir)
+	if err != nil {
+		t.Fatalf("keystore new key err : %s", err)
+	}
+	groupitem.UserSignPubkey = pubkey
+
+	var trxs []*quorumpb.Trx
+	for i := 0; i < count; i++ {
+		obj := &quorumpb.Object{Type: "Note", Content: "test content"}
+		trx, err := trxFactory.GetPostAnyTrx("", &quorumpb.Activity{Type: "Add", Object: obj, Target: &quorumpb.Object{Id: groupitem.GroupId, Type: "Group"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		trxs = append(trxs, trx)
+	}
+	return trxs
+}
+
+func TestVerifyTrxs(t *testing.T) {
+	trxs := getTestTrxs(t, 16)
+	trxs[5].TrxId = "modified"
+
+	results, err := VerifyTrxs(context.Background(), trxs, &BatchVerifyOptions{Workers: 4})
+	if err != nil {
+		t.Fatalf("verify trxs err: %s", err)
+	}
+	for i, result := range results {
+		if result.Index != i || result.Valid != (i != 5) {
+			t.Errorf("unexpected result of trx %d: %v", i, result)
+		}
+	}
+
+	results, err = VerifyTrxs(context.Background(), trxs, &BatchVerifyOptions{Workers: 1, StopOnFailure: true})
+	if err == nil || results[5].Valid {
+		t.Errorf("invalid trx not reported")
+	}
+	if !errors.Is(results[len(results)-1].Err, ErrVerifySkipped) {
+		t.Errorf("trx verified after the first failure: %v", results[len(results)-1])
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := VerifyTrxs(ctx, trxs, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled verification returns %v", err)
+	}
+}
+
+func TestVerifyBlocks(t *testing.T) {
+	seed, blocks := getTestChain(t, 8)
+	blocks = append([]*quorumpb.Block{seed.GenesisBlock}, blocks...)
+
+	results, err := VerifyBlocks(context.Background(), blocks, &BatchVerifyOptions{WithTrxs: true})
+	if err != nil {
+		t.Fatalf("verify blocks err: %s", err)
+	}
+	for i, result := range results {
+		if !result.Valid {
+			t.Errorf("block %d invalid: %s", i, result.Err)
+		}
+	}
+
+	blocks[3].TimeStamp++
+	results, err = VerifyBlocks(context.Background(), blocks, &BatchVerifyOptions{StopOnFailure: true})
+	if err == nil || results[3].Valid {
+		t.Errorf("tampered block not reported")
+	}
+}
+
+func BenchmarkVerifyTrxsSerial(b *testing.B) {
+	trxs := getTestTrxs(b, 256)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, trx := range trxs {
+			if ok, err := VerifyTrx(trx); !ok || err != nil {
+				b.Fatalf("verify trx failed: %v", err)
+			}
+		}
+	}
+}
+
+func BenchmarkVerifyTrxsParallel(b *testing.B) {
+	trxs := getTestTrxs(b, 256)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := VerifyTrxs(context.Background(), trxs, &BatchVerifyOptions{StopOnFailure: true}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkVerifyBlocksSerial(b *testing.B) {
+	_, blocks := getTestChain(b, 256)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, block := range blocks {
+			if err := verifyBlock(block, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkVerifyBlocksParallel(b *testing.B) {
+	_, blocks := getTestChain(b, 256)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := VerifyBlocks(context.Background(), blocks, &BatchVerifyOptions{StopOnFailure: true}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -2,11 +2,9 @@ package data
 
 import (
 	"bytes"
-	"encoding/base64"
 	"errors"
-	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"fmt"
 	guuid "github.com/google/uuid"
-	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
 	localcrypto "github.com/rumsystem/keystore/pkg/crypto"
 	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
 	"google.golang.org/protobuf/proto"
@@ -126,45 +124,40 @@ func VerifyBlockSign(block *quorumpb.Block) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	bytespubkey, err := base64.RawURLEncoding.DecodeString(block.ProducerPubKey)
-	if err == nil { //try eth key
-		ethpubkey, err := ethcrypto.DecompressPubkey(bytespubkey)
-		if err == nil {
-			ks := localcrypto.GetKeystore()
-			r := ks.EthVerifySign(hash, block.Signature, ethpubkey)
-			return r, nil
-		}
-	}
-
-	//libp2p key for backward campatibility
-	serializedpub, err := p2pcrypto.ConfigDecodeKey(block.ProducerPubKey)
+	pubkey, err := DefaultPubkeyResolver.Resolve(block.ProducerPubKey)
 	if err != nil {
-		return false, err
+		return false, blockVerifyError(block.BlockId, err)
 	}
-
-	pubkey, err := p2pcrypto.UnmarshalPublicKey(serializedpub)
-	if err != nil {
-		return false, err
+	if ok, err := pubkey.Verify(hash, block.Signature); !ok {
+		return false, blockVerifyError(block.BlockId, err)
 	}
-	return pubkey.Verify(hash, block.Signature)
+	return true, nil
 }
 
-func IsBlockValid(newBlock, oldBlock *quorumpb.Block) (bool, error) {
+// BlockValidator is an additional check of IsBlockValid, such as the producer schedule of the group
+type BlockValidator func(newBlock, oldBlock *quorumpb.Block) error
+
+func IsBlockValid(newBlock, oldBlock *quorumpb.Block, validators ...BlockValidator) (bool, error) {
 	hash, err := BlockHash(newBlock)
 	if err != nil {
 		return false, err
 	}
 
 	if res := bytes.Compare(hash, newBlock.Hash); res != 0 {
-		return false, errors.New("Hash for new block is invalid")
+		return false, blockVerifyError(newBlock.BlockId, ErrHashMismatch)
 	}
 
 	if res := bytes.Compare(newBlock.PreviousHash, oldBlock.Hash); res != 0 {
-		return false, errors.New("PreviousHash mismatch")
+		return false, blockVerifyError(newBlock.BlockId, ErrPrevHashMismatch)
 	}
 
 	if newBlock.PrevBlockId != oldBlock.BlockId {
-		return false, errors.New("Previous BlockId mismatch")
+		return false, blockVerifyError(newBlock.BlockId, fmt.Errorf("%w: previous block %s, expect %s", ErrPrevHashMismatch, newBlock.PrevBlockId, oldBlock.BlockId))
+	}
+	for _, validator := range validators {
+		if err := validator(newBlock, oldBlock); err != nil {
+			return false, blockVerifyError(newBlock.BlockId, err)
+		}
 	}
 	return VerifyBlockSign(newBlock)
 }
@@ -0,0 +1,53 @@
+package data
+
+import (
+	"fmt"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// BLOCK_MSG_VERSION is the Version of the BlockProduced and BlockSynced wrappers. Old nodes send a raw
+// Block instead, whose field 2 is the GroupId string: decoded as a wrapper, its Version is always 0.
+const BLOCK_MSG_VERSION = 1
+
+// GetBlockFromProducedData returns the block carried by the (decrypted) data of a BLOCK_PRODUCED trx.
+// Data is a quorumpb.BlockProduced of version BLOCK_MSG_VERSION, or a raw quorumpb.Block sent by old nodes.
+func GetBlockFromProducedData(data []byte) (*quorumpb.Block, error) {
+	wrapper := &quorumpb.BlockProduced{}
+	if err := proto.Unmarshal(data, wrapper); err == nil && wrapper.Version != 0 {
+		return wrappedBlock(wrapper.Version, wrapper.BlockItem, quorumpb.TrxType_BLOCK_PRODUCED)
+	}
+	return unmarshalRawBlock(data, quorumpb.TrxType_BLOCK_PRODUCED)
+}
+
+// GetBlockFromSyncedData returns the block carried by the (decrypted) data of a BLOCK_SYNCED trx.
+// Data is a quorumpb.BlockSynced of version BLOCK_MSG_VERSION, or a raw quorumpb.Block sent by old nodes.
+func GetBlockFromSyncedData(data []byte) (*quorumpb.Block, error) {
+	wrapper := &quorumpb.BlockSynced{}
+	if err := proto.Unmarshal(data, wrapper); err == nil && wrapper.Version != 0 {
+		return wrappedBlock(wrapper.Version, wrapper.BlockItem, quorumpb.TrxType_BLOCK_SYNCED)
+	}
+	return unmarshalRawBlock(data, quorumpb.TrxType_BLOCK_SYNCED)
+}
+
+func wrappedBlock(version uint32, blk *quorumpb.Block, trxtype quorumpb.TrxType) (*quorumpb.Block, error) {
+	if version != BLOCK_MSG_VERSION {
+		return nil, fmt.Errorf("unsupported %s data version %d", trxtype, version)
+	}
+	if blk == nil || blk.BlockId == "" {
+		return nil, fmt.Errorf("no block found in %s data", trxtype)
+	}
+	return blk, nil
+}
+
+func unmarshalRawBlock(data []byte, trxtype quorumpb.TrxType) (*quorumpb.Block, error) {
+	blk := &quorumpb.Block{}
+	if err := proto.Unmarshal(data, blk); err != nil {
+		return nil, fmt.Errorf("unmarshal %s data failed: %s", trxtype, err)
+	}
+	if blk.BlockId == "" {
+		return nil, fmt.Errorf("no block found in %s data", trxtype)
+	}
+	return blk, nil
+}
@@ -0,0 +1,78 @@
+package data
+
+import (
+	"testing"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+func getTestBlock() *quorumpb.Block {
+	trx := &quorumpb.Trx{TrxId: "b9a2e3f0-1111-4b80-81fb-d6347d0380b5", GroupId: "7c352591-f237-4b80-81fb-d6347d0380b5", Data: []byte("data")}
+	return &quorumpb.Block{
+		BlockId:        "1f3e0c6a-2222-4b80-81fb-d6347d0380b5",
+		GroupId:        "7c352591-f237-4b80-81fb-d6347d0380b5",
+		PrevBlockId:    "5d1a7a9e-3333-4b80-81fb-d6347d0380b5",
+		PreviousHash:   []byte("previoushash"),
+		Trxs:           []*quorumpb.Trx{trx},
+		ProducerPubKey: "producer",
+		Hash:           []byte("hash"),
+		Signature:      []byte("signature"),
+		TimeStamp:      1658000000000000000,
+	}
+}
+
+func TestGetBlockFromBlockData(t *testing.T) {
+	blk := getTestBlock()
+
+	rawdata, _ := proto.Marshal(blk)
+	produceddata, _ := proto.Marshal(&quorumpb.BlockProduced{BlockItem: blk, Version: BLOCK_MSG_VERSION})
+	synceddata, _ := proto.Marshal(&quorumpb.BlockSynced{BlockItem: blk, Version: BLOCK_MSG_VERSION})
+
+	cases := []struct {
+		name  string
+		parse func([]byte) (*quorumpb.Block, error)
+		data  []byte
+	}{
+		{"produced", GetBlockFromProducedData, produceddata},
+		{"produced old encoding", GetBlockFromProducedData, rawdata},
+		{"synced", GetBlockFromSyncedData, synceddata},
+		{"synced old encoding", GetBlockFromSyncedData, rawdata},
+	}
+	for _, c := range cases {
+		result, err := c.parse(c.data)
+		if err != nil {
+			t.Errorf("%s: parse block err: %s", c.name, err)
+			continue
+		}
+		if !proto.Equal(result, blk) {
+			t.Errorf("%s: block mismatch", c.name)
+		}
+	}
+
+	if _, err := GetBlockFromProducedData([]byte("not a block")); err == nil {
+		t.Errorf("parse invalid data should fail")
+	}
+	future, _ := proto.Marshal(&quorumpb.BlockProduced{BlockItem: blk, Version: BLOCK_MSG_VERSION + 1})
+	if _, err := GetBlockFromProducedData(future); err == nil {
+		t.Errorf("parse unknown version should fail")
+	}
+}
+
+// a raw block whose BlockId is itself an encoded block parses cleanly as a wrapper without version
+func TestGetBlockFromAmbiguousData(t *testing.T) {
+	inner, _ := proto.Marshal(&quorumpb.Block{BlockId: "inner"})
+	blk := &quorumpb.Block{BlockId: string(inner)}
+	rawdata, _ := proto.Marshal(blk)
+
+	wrapper := &quorumpb.BlockProduced{}
+	if err := proto.Unmarshal(rawdata, wrapper); err != nil || wrapper.BlockItem.GetBlockId() != "inner" || len(wrapper.ProtoReflect().GetUnknown()) != 0 {
+		t.Fatalf("raw block is not ambiguous: %v", err)
+	}
+	for name, parse := range map[string]func([]byte) (*quorumpb.Block, error){"produced": GetBlockFromProducedData, "synced": GetBlockFromSyncedData} {
+		result, err := parse(rawdata)
+		if err != nil || !proto.Equal(result, blk) {
+			t.Errorf("%s: ambiguous raw block misread as %v, %v", name, result, err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package data
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	localcrypto "github.com/rumsystem/keystore/pkg/crypto"
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+const CIPHER_KEY_LENGTH = 32
+
+// NewCipherKey returns a random hex encoded AES key for a group
+func NewCipherKey() (string, error) {
+	key := make([]byte, CIPHER_KEY_LENGTH)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// CipherKeyRing keeps every cipher key epoch of a group known by the local node,
+// trxs are decrypted with the key of their CipherKeyEpoch.
+// A new epoch is announced by a CIPHER_KEY trx sent by the group owner, with the new key
+// encrypted by age to the group members, so a removed member can not read the content of later epochs.
+type CipherKeyRing struct {
+	groupId     string
+	ownerPubkey string
+
+	mu     sync.RWMutex
+	keys   map[int64]string
+	latest int64
+}
+
+// NewCipherKeyRing creates a ring with the cipher key of groupItem
+func NewCipherKeyRing(groupItem *quorumpb.GroupItem) *CipherKeyRing {
+	ring := &CipherKeyRing{
+		groupId:     groupItem.GroupId,
+		ownerPubkey: groupItem.OwnerPubKey,
+		keys:        make(map[int64]string),
+	}
+	ring.keys[groupItem.CipherKeyEpoch] = groupItem.CipherKey
+	ring.latest = groupItem.CipherKeyEpoch
+	return ring
+}
+
+// Key returns the hex encoded cipher key of an epoch
+func (ring *CipherKeyRing) Key(epoch int64) (string, bool) {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+	key, ok := ring.keys[epoch]
+	return key, ok
+}
+
+// Latest returns the latest epoch and its cipher key
+func (ring *CipherKeyRing) Latest() (int64, string) {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+	return ring.latest, ring.keys[ring.latest]
+}
+
+// UpdateGroupItem sets the latest cipher key to groupItem, so new trxs are encrypted with it
+func (ring *CipherKeyRing) UpdateGroupItem(groupItem *quorumpb.GroupItem) {
+	groupItem.CipherKeyEpoch, groupItem.CipherKey = ring.Latest()
+}
+
+// DecryptTrx decrypts the data of a trx encrypted with the group cipher key
+func (ring *CipherKeyRing) DecryptTrx(trx *quorumpb.Trx) ([]byte, error) {
+	if trx.GroupId != ring.groupId {
+		return nil, fmt.Errorf("trx %s for group %s, expect group %s", trx.TrxId, trx.GroupId, ring.groupId)
+	}
+	key, ok := ring.Key(trx.CipherKeyEpoch)
+	if !ok {
+		return nil, fmt.Errorf("no cipher key of epoch %d for trx %s", trx.CipherKeyEpoch, trx.TrxId)
+	}
+	cipherKey, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+	return localcrypto.AesDecode(trx.Data, cipherKey)
+}
+
+// ApplyTrx applies a CIPHER_KEY trx, the new key is decrypted by the local encrypt key
+// of keyalias, or of the group if keyalias is empty
+func (ring *CipherKeyRing) ApplyTrx(trx *quorumpb.Trx, keyalias string) error {
+	if trx.Type != quorumpb.TrxType_CIPHER_KEY {
+		return fmt.Errorf("trx %s is %s, not %s", trx.TrxId, trx.Type, quorumpb.TrxType_CIPHER_KEY)
+	}
+	data, err := ring.DecryptTrx(trx)
+	if err != nil {
+		return err
+	}
+	item := &quorumpb.CipherKeyItem{}
+	if err := proto.Unmarshal(data, item); err != nil {
+		return err
+	}
+	return ring.ApplyCipherKeyItem(trx.SenderPubkey, item, keyalias)
+}
+
+// ApplyCipherKeyItem adds the epoch of item, only the group owner can send it
+func (ring *CipherKeyRing) ApplyCipherKeyItem(sender string, item *quorumpb.CipherKeyItem, keyalias string) error {
+	if item.GroupId != ring.groupId {
+		return fmt.Errorf("cipher key item for group %s, expect group %s", item.GroupId, ring.groupId)
+	}
+	if sender != ring.ownerPubkey || item.OwnerPubkey != ring.ownerPubkey {
+		return fmt.Errorf("cipher key item must be sent by group owner %s", ring.ownerPubkey)
+	}
+
+	ring.mu.RLock()
+	latest := ring.latest
+	ring.mu.RUnlock()
+	if item.Epoch <= latest {
+		return fmt.Errorf("cipher key epoch %d is not after epoch %d", item.Epoch, latest)
+	}
+
+	ks := localcrypto.GetKeystore()
+	var keybytes []byte
+	var err error
+	if keyalias == "" {
+		keybytes, err = ks.Decrypt(ring.groupId, item.EncryptedKey)
+	} else {
+		keybytes, err = ks.DecryptByAlias(keyalias, item.EncryptedKey)
+	}
+	if err != nil {
+		return fmt.Errorf("decrypt cipher key of epoch %d failed, not a recipient: %s", item.Epoch, err)
+	}
+	key := string(keybytes)
+	if decoded, err := hex.DecodeString(key); err != nil || len(decoded) != CIPHER_KEY_LENGTH {
+		return fmt.Errorf("invalid cipher key of epoch %d", item.Epoch)
+	}
+
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+	if item.Epoch <= ring.latest {
+		return fmt.Errorf("cipher key epoch %d is not after epoch %d", item.Epoch, ring.latest)
+	}
+	ring.keys[item.Epoch] = key
+	ring.latest = item.Epoch
+	return nil
+}
@@ -0,0 +1,150 @@
+package data
+
+import (
+	"testing"
+
+	localcrypto "github.com/rumsystem/keystore/pkg/crypto"
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestCipherKeyRotation(t *testing.T) {
+	keystoreDir := t.TempDir()
+	tn := &TestNonce{}
+	trxFactory := &TrxFactory{}
+	groupitem := GetGroupItem()
+	trxFactory.Init("1.0.0", groupitem, "default", tn)
+	_, pubkey, err := GetKeyStorePubKey(groupitem.GroupId, keystoreDir)
+	if err != nil {
+		t.Fatalf("keystore new key err : %s", err)
+	}
+	groupitem.UserSignPubkey = pubkey
+	groupitem.OwnerPubKey = pubkey
+
+	ks := localcrypto.GetKeystore().(*localcrypto.DirKeyStore)
+	if _, err := ks.NewKeyWithDefaultPassword(groupitem.GroupId, localcrypto.Encrypt); err != nil {
+		t.Fatalf("keystore new encrypt key err : %s", err)
+	}
+	encryptpubkey, err := ks.GetEncodedPubkey(groupitem.GroupId, localcrypto.Encrypt)
+	if err != nil {
+		t.Fatalf("get encrypt pubkey err : %s", err)
+	}
+	otherkeyname := groupitem.GroupId + "_other"
+	if _, err := ks.NewKeyWithDefaultPassword(otherkeyname, localcrypto.Encrypt); err != nil {
+		t.Fatalf("keystore new encrypt key err : %s", err)
+	}
+	otherpubkey, err := ks.GetEncodedPubkey(otherkeyname, localcrypto.Encrypt)
+	if err != nil {
+		t.Fatalf("get encrypt pubkey err : %s", err)
+	}
+
+	ring := NewCipherKeyRing(groupitem)
+	obj := &quorumpb.Object{Type: "Note", Content: "test content"}
+	oldtrx, err := trxFactory.GetPostAnyTrx("", obj)
+	if err != nil {
+		t.Fatalf("create post err: %s", err)
+	}
+
+	newkey, err := NewCipherKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keytrx, err := trxFactory.GetCipherKeyTrx("", newkey, []string{encryptpubkey})
+	if err != nil {
+		t.Fatalf("create cipher key trx err: %s", err)
+	}
+	if ok, err := VerifyTrx(keytrx); !ok {
+		t.Fatalf("verify cipher key trx err: %v", err)
+	}
+
+	//a removed member is not a recipient of the new key
+	outsider := NewCipherKeyRing(groupitem)
+	outsidertrx, err := trxFactory.GetCipherKeyTrx("", newkey, []string{otherpubkey})
+	if err != nil {
+		t.Fatalf("create cipher key trx err: %s", err)
+	}
+	if err := outsider.ApplyTrx(outsidertrx, ""); err == nil {
+		t.Errorf("non recipient should not get the new cipher key")
+	}
+
+	if err := ring.ApplyTrx(keytrx, ""); err != nil {
+		t.Fatalf("apply cipher key trx err: %s", err)
+	}
+	if err := ring.ApplyTrx(keytrx, ""); err == nil {
+		t.Errorf("apply the same epoch twice should fail")
+	}
+	ring.UpdateGroupItem(groupitem)
+	if groupitem.CipherKeyEpoch != 1 || groupitem.CipherKey != newkey {
+		t.Fatalf("group item not updated to the new cipher key")
+	}
+
+	newtrx, err := trxFactory.GetPostAnyTrx("", obj)
+	if err != nil {
+		t.Fatalf("create post err: %s", err)
+	}
+	if newtrx.CipherKeyEpoch != 1 {
+		t.Errorf("new post encrypted with epoch %d, expect 1", newtrx.CipherKeyEpoch)
+	}
+	if ok, err := VerifyTrx(newtrx); !ok {
+		t.Errorf("verify post err: %v", err)
+	}
+	for _, trx := range []*quorumpb.Trx{oldtrx, newtrx} {
+		if _, err := ring.DecryptTrx(trx); err != nil {
+			t.Errorf("decrypt trx of epoch %d err: %s", trx.CipherKeyEpoch, err)
+		}
+	}
+	if _, err := outsider.DecryptTrx(newtrx); err == nil {
+		t.Errorf("decrypt trx of unknown epoch should fail")
+	}
+
+	//a member removed from the membership is not a recipient of the next key
+	state := NewMembershipState(groupitem.GroupId, pubkey)
+	member := "member_pubkey"
+	for i, apply := range []func(int64) error{
+		func(h int64) error {
+			return state.ApplyUserItem(h, pubkey, &quorumpb.UserItem{GroupId: groupitem.GroupId, UserPubkey: pubkey, EncryptPubkey: encryptpubkey, GroupOwnerPubkey: pubkey, Action: quorumpb.ActionType_ADD})
+		},
+		func(h int64) error {
+			return state.ApplyUserItem(h, pubkey, &quorumpb.UserItem{GroupId: groupitem.GroupId, UserPubkey: member, EncryptPubkey: otherpubkey, GroupOwnerPubkey: pubkey, Action: quorumpb.ActionType_ADD})
+		},
+		func(h int64) error {
+			return state.ApplyAnnounceItem(h, member, &quorumpb.AnnounceItem{GroupId: groupitem.GroupId, SignPubkey: member, EncryptPubkey: otherpubkey, Type: quorumpb.AnnounceType_AS_USER_ENCRYPT, Result: quorumpb.ApproveType_ANNOUNCED})
+		},
+		func(h int64) error {
+			return state.ApplyAnnounceItem(h, pubkey, &quorumpb.AnnounceItem{GroupId: groupitem.GroupId, SignPubkey: member, Type: quorumpb.AnnounceType_AS_USER_ENCRYPT, OwnerPubkey: pubkey, Result: quorumpb.ApproveType_APPROVED})
+		},
+		func(h int64) error {
+			return state.ApplyUserItem(h, pubkey, &quorumpb.UserItem{GroupId: groupitem.GroupId, UserPubkey: member, GroupOwnerPubkey: pubkey, Action: quorumpb.ActionType_REMOVE})
+		},
+	} {
+		if err := apply(int64(i + 1)); err != nil {
+			t.Fatalf("apply membership step %d err: %s", i, err)
+		}
+	}
+	if keys := state.EncryptPubkeys(); len(keys) != 1 || keys[0] != encryptpubkey {
+		t.Fatalf("EncryptPubkeys after removing the member got %v", keys)
+	}
+	trxFactory.SetRecipientResolver(state)
+	nextkey, err := NewCipherKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nexttrx, err := trxFactory.GetCipherKeyTrx("", nextkey)
+	if err != nil {
+		t.Fatalf("create cipher key trx err: %s", err)
+	}
+	data, err := ring.DecryptTrx(nexttrx)
+	if err != nil {
+		t.Fatalf("decrypt cipher key trx err: %s", err)
+	}
+	item := &quorumpb.CipherKeyItem{}
+	if err := proto.Unmarshal(data, item); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Decrypt(otherkeyname, item.EncryptedKey); err == nil {
+		t.Errorf("removed member should not get the next cipher key")
+	}
+	if err := ring.ApplyTrx(nexttrx, ""); err != nil {
+		t.Errorf("apply next cipher key trx err: %s", err)
+	}
+}
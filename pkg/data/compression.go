@@ -0,0 +1,210 @@
+package data
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DECOMPRESS_SIZE_LIMIT bounds the total size of the File and Image contents
+// decompressed from one POST, to protect receivers from decompression bombs
+const DECOMPRESS_SIZE_LIMIT = 20 * 1024 * 1024 //(20Mb)
+
+// name of the single entry of a zip compressed content
+const zipEntryName = "content"
+
+// CompressContent returns a copy of content where the File and Image payloads with a Compression
+// are compressed by it and marked as Compressed, whatever their content: a payload which is already a
+// compressed file is compressed again, so that it is received unchanged. Payloads already marked are kept as is.
+// GetPostAnyTrx calls it on every POST content.
+func CompressContent(content proto.Message) (proto.Message, error) {
+	compressed := proto.Clone(content)
+	err := walkPayloads(compressed.ProtoReflect(), func(payload payloadCompression, data []byte) ([]byte, error) {
+		if payload.Compression == quorumpb.File_none || *payload.Compressed {
+			return data, nil
+		}
+		*payload.Compressed = true
+		return compressBytes(payload.Compression, data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return compressed, nil
+}
+
+// DecompressContent returns a copy of content where the File and Image payloads marked as Compressed are
+// decompressed and unmarked, the Compression of each payload is kept. Payloads which are not marked are kept as is.
+// It fails if the decompressed payloads are over DECOMPRESS_SIZE_LIMIT.
+func DecompressContent(content proto.Message) (proto.Message, error) {
+	decompressed := proto.Clone(content)
+	var budget int64 = DECOMPRESS_SIZE_LIMIT
+	err := walkPayloads(decompressed.ProtoReflect(), func(payload payloadCompression, data []byte) ([]byte, error) {
+		if !*payload.Compressed {
+			return data, nil
+		}
+		*payload.Compressed = false
+		plain, err := decompressBytes(payload.Compression, data, budget)
+		if err != nil {
+			return nil, err
+		}
+		budget -= int64(len(plain))
+		return plain, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decompressed, nil
+}
+
+// DecodePostContent decodes the (decrypted) data of a POST trx and decompresses its File and Image payloads
+func DecodePostContent(trxid string, data []byte) (proto.Message, string, error) {
+	content, typeurl, err := quorumpb.BytesToMessage(trxid, data)
+	if err != nil {
+		return nil, "", err
+	}
+	content, err = DecompressContent(content)
+	if err != nil {
+		return nil, "", fmt.Errorf("decompress content of trx %s failed: %s", trxid, err)
+	}
+	return content, typeurl, nil
+}
+
+// the compression fields of a File or an Image
+type payloadCompression struct {
+	Compression quorumpb.File_Compression
+	Compressed  *bool
+}
+
+// calls fn on the content of every File and Image found in m, and replaces the content by its result
+func walkPayloads(m protoreflect.Message, fn func(payload payloadCompression, data []byte) ([]byte, error)) error {
+	var err error
+	switch payload := m.Interface().(type) {
+	case *quorumpb.File:
+		payload.Content, err = fn(payloadCompression{payload.Compression, &payload.Compressed}, payload.Content)
+	case *quorumpb.Image:
+		payload.Content, err = fn(payloadCompression{payload.Compression, &payload.Compressed}, payload.Content)
+	}
+	if err != nil {
+		return err
+	}
+
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Kind() != protoreflect.MessageKind {
+			return true
+		}
+		if fd.IsList() {
+			list := v.List()
+			for i := 0; i < list.Len() && err == nil; i++ {
+				err = walkPayloads(list.Get(i).Message(), fn)
+			}
+		} else if !fd.IsMap() {
+			err = walkPayloads(v.Message(), fn)
+		}
+		return err == nil
+	})
+	return err
+}
+
+func compressBytes(compression quorumpb.File_Compression, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	switch compression {
+	case quorumpb.File_none:
+		return data, nil
+	case quorumpb.File_gz:
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case quorumpb.File_zip:
+		zw := zip.NewWriter(&buf)
+		w, err := zw.Create(zipEntryName)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	case quorumpb.File_zstd:
+		w, err := zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			w.Close()
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression %s", compression)
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressBytes fails if the decompressed content is over limit bytes
+func decompressBytes(compression quorumpb.File_Compression, data []byte, limit int64) ([]byte, error) {
+	var r io.Reader
+	switch compression {
+	case quorumpb.File_none:
+		if int64(len(data)) > limit {
+			return nil, fmt.Errorf("%w: content size over %d bytes", ErrOversize, limit)
+		}
+		return data, nil
+	case quorumpb.File_gz:
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	case quorumpb.File_zip:
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		if len(zr.File) != 1 {
+			return nil, fmt.Errorf("zip content must have 1 entry, got %d", len(zr.File))
+		}
+		if zr.File[0].UncompressedSize64 > uint64(limit) {
+			return nil, fmt.Errorf("%w: decompressed content size over %d bytes", ErrOversize, limit)
+		}
+		fr, err := zr.File[0].Open()
+		if err != nil {
+			return nil, err
+		}
+		defer fr.Close()
+		r = fr
+	case quorumpb.File_zstd:
+		zr, err := zstd.NewReader(bytes.NewReader(data), zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(uint64(limit)+1))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, fmt.Errorf("unsupported compression %s", compression)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > limit {
+		return nil, fmt.Errorf("%w: decompressed content size over %d bytes", ErrOversize, limit)
+	}
+	return content, nil
+}
@@ -0,0 +1,151 @@
+package data
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+)
+
+func TestCompressContent(t *testing.T) {
+	filecontent := bytes.Repeat([]byte("test file content "), 1024)
+	imagecontent := bytes.Repeat([]byte{0x89, 'P', 'N', 'G'}, 1024)
+
+	for _, compression := range []quorumpb.File_Compression{quorumpb.File_gz, quorumpb.File_zip, quorumpb.File_zstd} {
+		obj := &quorumpb.Object{
+			Type:        "Note",
+			File:        &quorumpb.File{Name: "a.txt", Compression: compression, Content: filecontent},
+			Image:       []*quorumpb.Image{{Name: "a.png", MediaType: "image/png", Compression: compression, Content: imagecontent}},
+			Attachments: []*quorumpb.Object{{File: &quorumpb.File{Name: "b.txt", Compression: quorumpb.File_none, Content: filecontent}}},
+		}
+		postobj := &quorumpb.Activity{Type: "Add", Object: obj}
+
+		msg, err := CompressContent(postobj)
+		if err != nil {
+			t.Fatalf("compress %s content err: %s", compression, err)
+		}
+		compressed := msg.(*quorumpb.Activity)
+		if len(compressed.Object.File.Content) >= len(filecontent) || len(compressed.Object.Image[0].Content) >= len(imagecontent) {
+			t.Errorf("%s payloads not compressed", compression)
+		}
+		if !bytes.Equal(compressed.Object.Attachments[0].File.Content, filecontent) {
+			t.Errorf("payload without compression should be kept as is")
+		}
+		if !bytes.Equal(obj.File.Content, filecontent) {
+			t.Errorf("compress should not modify the original content")
+		}
+
+		//compressing twice keeps the payloads
+		again, err := CompressContent(compressed)
+		if err != nil {
+			t.Fatalf("compress %s content again err: %s", compression, err)
+		}
+		if !bytes.Equal(again.(*quorumpb.Activity).Object.File.Content, compressed.Object.File.Content) {
+			t.Errorf("%s payload compressed twice", compression)
+		}
+
+		data, err := quorumpb.ContentToBytes(compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, _, err := DecodePostContent("trxid", data)
+		if err != nil {
+			t.Fatalf("decode %s content err: %s", compression, err)
+		}
+		decodedobj := decoded.(*quorumpb.Activity).Object
+		if !bytes.Equal(decodedobj.File.Content, filecontent) || !bytes.Equal(decodedobj.Image[0].Content, imagecontent) {
+			t.Errorf("%s payloads not decompressed", compression)
+		}
+	}
+}
+
+func TestDecompressContentLimit(t *testing.T) {
+	bomb := make([]byte, DECOMPRESS_SIZE_LIMIT+1)
+	for _, compression := range []quorumpb.File_Compression{quorumpb.File_gz, quorumpb.File_zip, quorumpb.File_zstd} {
+		compressed, err := compressBytes(compression, bomb)
+		if err != nil {
+			t.Fatalf("compress %s err: %s", compression, err)
+		}
+		obj := &quorumpb.Object{File: &quorumpb.File{Compression: compression, Compressed: true, Content: compressed}}
+		if _, err := DecompressContent(obj); err == nil {
+			t.Errorf("%s content over the limit accepted", compression)
+		}
+	}
+
+	//the limit applies to all the payloads of a content
+	half, _ := compressBytes(quorumpb.File_gz, make([]byte, DECOMPRESS_SIZE_LIMIT/2+1))
+	obj := &quorumpb.Object{Image: []*quorumpb.Image{{Compression: quorumpb.File_gz, Compressed: true, Content: half}, {Compression: quorumpb.File_gz, Compressed: true, Content: half}}}
+	if _, err := DecompressContent(obj); err == nil {
+		t.Errorf("payloads over the limit accepted")
+	}
+}
+
+func TestCompressContentCompressedFiles(t *testing.T) {
+	//a zip archive of 2 entries
+	var zipfile bytes.Buffer
+	zw := zip.NewWriter(&zipfile)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(bytes.Repeat([]byte(name), 1024))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	//a .tar.gz archive
+	var targz bytes.Buffer
+	gw := gzip.NewWriter(&targz)
+	tw := tar.NewWriter(gw)
+	content := bytes.Repeat([]byte("tar content "), 1024)
+	if err := tw.WriteHeader(&tar.Header{Name: "a.txt", Mode: 0600, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write(content)
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []*quorumpb.File{
+		{Name: "a.zip", MediaType: "application/zip", Compression: quorumpb.File_zip, Content: zipfile.Bytes()},
+		{Name: "a.tar.gz", MediaType: "application/gzip", Compression: quorumpb.File_gz, Content: targz.Bytes()},
+	} {
+		msg, err := CompressContent(&quorumpb.Object{Type: "Document", File: file})
+		if err != nil {
+			t.Fatalf("compress %s err: %s", file.Name, err)
+		}
+		if !msg.(*quorumpb.Object).File.Compressed {
+			t.Errorf("%s not marked as compressed", file.Name)
+		}
+		data, err := quorumpb.ContentToBytes(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, _, err := DecodePostContent("trxid", data)
+		if err != nil {
+			t.Fatalf("decode %s err: %s", file.Name, err)
+		}
+		decodedfile := decoded.(*quorumpb.Object).File
+		if !bytes.Equal(decodedfile.Content, file.Content) || decodedfile.Compressed {
+			t.Errorf("%s not received unchanged", file.Name)
+		}
+	}
+
+	//payloads without the mark are not decompressed
+	obj := &quorumpb.Object{File: &quorumpb.File{Compression: quorumpb.File_gz, Content: targz.Bytes()}}
+	decoded, err := DecompressContent(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.(*quorumpb.Object).File.Content, targz.Bytes()) {
+		t.Errorf("payload without the compressed mark decompressed")
+	}
+}
@@ -0,0 +1,336 @@
+package data
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// Post is the current state of a posted object
+type Post struct {
+	ObjectId  string //Object.Id, or the id of the trx which created it
+	TrxId     string //trx which created the object
+	Sender    string
+	Object    *quorumpb.Object
+	ReplyTo   string //object id of the parent post
+	TimeStamp int64  //timestamp of the creating trx
+	Updated   int64  //timestamp of the last update trx
+	Deleted   bool
+	Likes     int
+	Dislikes  int
+
+	seq int64
+}
+
+// ContentIndex materializes the POST trxs of a group, applied in block order, into posts,
+// reply threads and reactions. Only the sender of a post can update or delete it.
+// A post is referred to by its object id or by the id of its creating trx, an object id can not be
+// the id of the creating trx of another post so both kinds of ids never collide.
+type ContentIndex struct {
+	groupId string
+
+	mu        sync.RWMutex
+	seq       int64
+	posts     map[string]*Post
+	trxposts  map[string]string            //creating trx id -> object id
+	applied   map[string]bool              //ids of the applied trxs
+	replies   map[string][]string          //object id -> reply object ids
+	reactions map[string]map[string]string //object id -> sender -> Like/Dislike
+}
+
+var _ ObjectLookup = (*ContentIndex)(nil)
+
+func NewContentIndex(groupId string) *ContentIndex {
+	return &ContentIndex{
+		groupId:   groupId,
+		posts:     make(map[string]*Post),
+		trxposts:  make(map[string]string),
+		applied:   make(map[string]bool),
+		replies:   make(map[string][]string),
+		reactions: make(map[string]map[string]string),
+	}
+}
+
+// ApplyTrxData decodes the (decrypted) data of a POST trx with DecodePostContent and applies it
+func (index *ContentIndex) ApplyTrxData(trx *quorumpb.Trx, data []byte) error {
+	content, _, err := DecodePostContent(trx.TrxId, data)
+	if err != nil {
+		return err
+	}
+	return index.Apply(trx, content)
+}
+
+// Apply applies the decoded content of a POST trx. An Object is a new post, an Activity
+// creates (Create, Add), updates, deletes or reacts (Like, Dislike) to a post, other verbs are ignored.
+// A reply must refer to a post already applied.
+func (index *ContentIndex) Apply(trx *quorumpb.Trx, content proto.Message) error {
+	if trx.GroupId != index.groupId {
+		return fmt.Errorf("trx %s for group %s, expect group %s", trx.TrxId, trx.GroupId, index.groupId)
+	}
+	if trx.Type != quorumpb.TrxType_POST {
+		return fmt.Errorf("trx %s is %s, not %s", trx.TrxId, trx.Type, quorumpb.TrxType_POST)
+	}
+
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	if index.applied[trx.TrxId] {
+		return fmt.Errorf("trx %s already applied", trx.TrxId)
+	}
+	if err := index.apply(trx, content); err != nil {
+		return err
+	}
+	index.applied[trx.TrxId] = true
+	return nil
+}
+
+// must be called with index.mu locked
+func (index *ContentIndex) apply(trx *quorumpb.Trx, content proto.Message) error {
+	switch c := content.(type) {
+	case *quorumpb.Object:
+		return index.create(trx, c, "")
+	case *quorumpb.Activity:
+		var inreplyto string
+		if c.InReplyTo != nil {
+			inreplyto = c.InReplyTo.Id
+		}
+		switch c.Type {
+		case "Create", "Add":
+			if c.Object == nil {
+				return fmt.Errorf("%s activity of trx %s has no object", c.Type, trx.TrxId)
+			}
+			return index.create(trx, c.Object, inreplyto)
+		case "Update":
+			if isProfileUpdate(c) {
+				return nil
+			}
+			return index.update(trx, c.Object)
+		case "Delete":
+			return index.delete(trx, c.Object)
+		case "Like", "Dislike":
+			return index.react(trx, c.Type, c.Object)
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported content %T of trx %s", content, trx.TrxId)
+}
+
+func (index *ContentIndex) create(trx *quorumpb.Trx, obj *quorumpb.Object, inreplyto string) error {
+	objectId := obj.Id
+	if objectId == "" {
+		objectId = trx.TrxId
+	}
+	if _, ok := index.posts[objectId]; ok {
+		return fmt.Errorf("object %s of trx %s already exists", objectId, trx.TrxId)
+	}
+	if _, ok := index.trxposts[objectId]; ok {
+		return fmt.Errorf("object id %s of trx %s is the id of the trx of another object", objectId, trx.TrxId)
+	}
+	if _, ok := index.posts[trx.TrxId]; ok {
+		return fmt.Errorf("trx id %s is the id of another object", trx.TrxId)
+	}
+	if obj.Inreplyto != nil && obj.Inreplyto.Trxid != "" {
+		inreplyto = obj.Inreplyto.Trxid
+	}
+	if inreplyto != "" {
+		//objectId is not indexed yet, so a reply can not be its own ancestor
+		parentId, ok := index.postId(inreplyto)
+		if !ok || index.posts[parentId].Deleted {
+			return fmt.Errorf("object %s of trx %s replies to unknown object %s", objectId, trx.TrxId, inreplyto)
+		}
+		inreplyto = parentId
+	}
+
+	index.seq++
+	index.posts[objectId] = &Post{
+		ObjectId:  objectId,
+		TrxId:     trx.TrxId,
+		Sender:    trx.SenderPubkey,
+		Object:    proto.Clone(obj).(*quorumpb.Object),
+		ReplyTo:   inreplyto,
+		TimeStamp: trx.TimeStamp,
+		seq:       index.seq,
+	}
+	index.trxposts[trx.TrxId] = objectId
+	if inreplyto != "" {
+		index.replies[inreplyto] = append(index.replies[inreplyto], objectId)
+	}
+	return nil
+}
+
+// postId returns the object id of a post referred to by its object id or the id of its creating trx
+func (index *ContentIndex) postId(id string) (string, bool) {
+	if _, ok := index.posts[id]; ok {
+		return id, true
+	}
+	objectId, ok := index.trxposts[id]
+	return objectId, ok
+}
+
+func (index *ContentIndex) ownedPost(trx *quorumpb.Trx, verb string, obj *quorumpb.Object) (*Post, error) {
+	post, err := index.existingPost(trx, verb, obj)
+	if err != nil {
+		return nil, err
+	}
+	if post.Sender != trx.SenderPubkey {
+		return nil, fmt.Errorf("%s of object %s by trx %s: only the sender of the object can do it", verb, post.ObjectId, trx.TrxId)
+	}
+	return post, nil
+}
+
+func (index *ContentIndex) existingPost(trx *quorumpb.Trx, verb string, obj *quorumpb.Object) (*Post, error) {
+	if obj == nil || obj.Id == "" {
+		return nil, fmt.Errorf("%s activity of trx %s has no object id", verb, trx.TrxId)
+	}
+	objectId, ok := index.postId(obj.Id)
+	if !ok {
+		return nil, fmt.Errorf("%s activity of trx %s refers to unknown object %s", verb, trx.TrxId, obj.Id)
+	}
+	post := index.posts[objectId]
+	if post.Deleted {
+		return nil, fmt.Errorf("%s activity of trx %s refers to unknown object %s", verb, trx.TrxId, obj.Id)
+	}
+	return post, nil
+}
+
+func (index *ContentIndex) update(trx *quorumpb.Trx, obj *quorumpb.Object) error {
+	post, err := index.ownedPost(trx, "Update", obj)
+	if err != nil {
+		return err
+	}
+	updated := proto.Clone(obj).(*quorumpb.Object)
+	updated.Id = post.Object.Id
+	if updated.Type == "" {
+		updated.Type = post.Object.Type
+	}
+	updated.Inreplyto = post.Object.Inreplyto
+	post.Object = updated
+	post.Updated = trx.TimeStamp
+	return nil
+}
+
+func (index *ContentIndex) delete(trx *quorumpb.Trx, obj *quorumpb.Object) error {
+	post, err := index.ownedPost(trx, "Delete", obj)
+	if err != nil {
+		return err
+	}
+	post.Deleted = true
+	post.Object = &quorumpb.Object{Id: post.Object.Id, Type: post.Object.Type}
+	post.Updated = trx.TimeStamp
+	return nil
+}
+
+// a sender has at most one reaction per post, the last one wins
+func (index *ContentIndex) react(trx *quorumpb.Trx, verb string, obj *quorumpb.Object) error {
+	post, err := index.existingPost(trx, verb, obj)
+	if err != nil {
+		return err
+	}
+	reactions, ok := index.reactions[post.ObjectId]
+	if !ok {
+		reactions = make(map[string]string)
+		index.reactions[post.ObjectId] = reactions
+	}
+	switch reactions[trx.SenderPubkey] {
+	case "Like":
+		post.Likes--
+	case "Dislike":
+		post.Dislikes--
+	}
+	reactions[trx.SenderPubkey] = verb
+	if verb == "Like" {
+		post.Likes++
+	} else {
+		post.Dislikes++
+	}
+	return nil
+}
+
+// ObjectExists implements ObjectLookup, an object id or the id of its creating trx can be used
+func (index *ContentIndex) ObjectExists(id string) bool {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+	objectId, ok := index.postId(id)
+	return ok && !index.posts[objectId].Deleted
+}
+
+// Get returns a copy of the post with the given object id
+func (index *ContentIndex) Get(objectId string) (*Post, bool) {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+	post, ok := index.posts[objectId]
+	if !ok {
+		return nil, false
+	}
+	return post.copy(), true
+}
+
+// Posts returns the top level posts which are not deleted, in block order
+func (index *ContentIndex) Posts() []*Post {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+	var posts []*Post
+	for _, post := range index.posts {
+		if post.ReplyTo == "" && !post.Deleted {
+			posts = append(posts, post.copy())
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool { return posts[i].seq < posts[j].seq })
+	return posts
+}
+
+// Replies returns the direct replies of a post which are not deleted, in block order
+func (index *ContentIndex) Replies(objectId string) []*Post {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+	var replies []*Post
+	for _, replyId := range index.replies[objectId] {
+		if post := index.posts[replyId]; !post.Deleted {
+			replies = append(replies, post.copy())
+		}
+	}
+	return replies
+}
+
+// Thread returns a post followed by all its replies which are not deleted, depth first in block order
+func (index *ContentIndex) Thread(objectId string) []*Post {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+	root, ok := index.posts[objectId]
+	if !ok {
+		return nil
+	}
+	thread := []*Post{root.copy()}
+	visited := map[string]bool{objectId: true}
+	//stack of the replies to visit, the next one on top
+	stack := reversed(index.replies[objectId])
+	for len(stack) > 0 {
+		replyId := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[replyId] {
+			continue
+		}
+		visited[replyId] = true
+		if post := index.posts[replyId]; !post.Deleted {
+			thread = append(thread, post.copy())
+		}
+		stack = append(stack, reversed(index.replies[replyId])...)
+	}
+	return thread
+}
+
+func reversed(ids []string) []string {
+	r := make([]string, len(ids))
+	for i, id := range ids {
+		r[len(ids)-1-i] = id
+	}
+	return r
+}
+
+func (post *Post) copy() *Post {
+	c := *post
+	c.Object = proto.Clone(post.Object).(*quorumpb.Object)
+	return &c
+}
@@ -0,0 +1,148 @@
+package data
+
+import (
+	"fmt"
+	"testing"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestContentIndex(t *testing.T) {
+	groupId := GetGroupItem().GroupId
+	index := NewContentIndex(groupId)
+
+	var nexttrx int
+	apply := func(sender string, content proto.Message) error {
+		nexttrx++
+		trx := &quorumpb.Trx{TrxId: string(rune('a' + nexttrx)), GroupId: groupId, Type: quorumpb.TrxType_POST, SenderPubkey: sender, TimeStamp: int64(nexttrx)}
+		data, err := quorumpb.ContentToBytes(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return index.ApplyTrxData(trx, data)
+	}
+	mustApply := func(sender string, content proto.Message) {
+		if err := apply(sender, content); err != nil {
+			t.Fatalf("apply content err: %s", err)
+		}
+	}
+
+	group := &quorumpb.Object{Id: groupId, Type: "Group"}
+	mustApply("alice", &quorumpb.Activity{Type: "Add", Object: &quorumpb.Object{Id: "post1", Type: "Note", Content: "hello"}, Target: group})
+	mustApply("bob", &quorumpb.Object{Id: "post2", Type: "Note", Content: "old style post"})
+	mustApply("bob", &quorumpb.Activity{Type: "Add", Object: &quorumpb.Object{Id: "reply1", Type: "Note", Content: "hi", Inreplyto: &quorumpb.Reply{Trxid: "b"}}, Target: group})
+	mustApply("alice", &quorumpb.Activity{Type: "Add", Object: &quorumpb.Object{Id: "reply2", Type: "Note", Content: "hi again"}, InReplyTo: &quorumpb.Object{Id: "reply1"}, Target: group})
+	mustApply("bob", &quorumpb.Activity{Type: "Like", Object: &quorumpb.Object{Id: "post1"}})
+	mustApply("carol", &quorumpb.Activity{Type: "Like", Object: &quorumpb.Object{Id: "post1"}})
+	mustApply("carol", &quorumpb.Activity{Type: "Dislike", Object: &quorumpb.Object{Id: "post1"}})
+	mustApply("alice", &quorumpb.Activity{Type: "Update", Object: &quorumpb.Object{Id: "post1", Content: "hello world"}})
+
+	if err := apply("bob", &quorumpb.Activity{Type: "Update", Object: &quorumpb.Object{Id: "post1", Content: "hacked"}}); err == nil {
+		t.Errorf("update by another sender accepted")
+	}
+	if err := apply("bob", &quorumpb.Activity{Type: "Delete", Object: &quorumpb.Object{Id: "post1"}}); err == nil {
+		t.Errorf("delete by another sender accepted")
+	}
+	if err := apply("bob", &quorumpb.Object{Id: "post1", Type: "Note"}); err == nil {
+		t.Errorf("duplicated object id accepted")
+	}
+
+	post, ok := index.Get("post1")
+	if !ok {
+		t.Fatalf("post1 not found")
+	}
+	if post.Object.Content != "hello world" || post.Object.Type != "Note" || post.Likes != 1 || post.Dislikes != 1 {
+		t.Errorf("unexpected post1 state: %+v", post)
+	}
+
+	thread := index.Thread("post1")
+	if len(thread) != 3 || thread[1].ObjectId != "reply1" || thread[2].ObjectId != "reply2" {
+		t.Errorf("unexpected thread of post1: %v", thread)
+	}
+	if posts := index.Posts(); len(posts) != 2 || posts[0].ObjectId != "post1" || posts[1].ObjectId != "post2" {
+		t.Errorf("unexpected top level posts: %v", posts)
+	}
+
+	mustApply("bob", &quorumpb.Activity{Type: "Delete", Object: &quorumpb.Object{Id: "reply1"}})
+	if index.ObjectExists("reply1") {
+		t.Errorf("deleted post still exists")
+	}
+	if replies := index.Replies("post1"); len(replies) != 0 {
+		t.Errorf("deleted reply still listed")
+	}
+	if err := apply("alice", &quorumpb.Activity{Type: "Like", Object: &quorumpb.Object{Id: "reply1"}}); err == nil {
+		t.Errorf("like of a deleted post accepted")
+	}
+	if err := apply("alice", &quorumpb.Object{Id: "reply3", Type: "Note", Inreplyto: &quorumpb.Reply{Trxid: "reply1"}}); err == nil {
+		t.Errorf("reply to a deleted post accepted")
+	}
+}
+
+func TestContentIndexReplies(t *testing.T) {
+	groupId := GetGroupItem().GroupId
+	index := NewContentIndex(groupId)
+	apply := func(trxid string, sender string, content proto.Message) error {
+		trx := &quorumpb.Trx{TrxId: trxid, GroupId: groupId, Type: quorumpb.TrxType_POST, SenderPubkey: sender}
+		return index.Apply(trx, content)
+	}
+	note := func(id string, inreplyto string) *quorumpb.Object {
+		obj := &quorumpb.Object{Id: id, Type: "Note", Content: id}
+		if inreplyto != "" {
+			obj.Inreplyto = &quorumpb.Reply{Trxid: inreplyto}
+		}
+		return obj
+	}
+
+	if err := apply("trx1", "alice", note("post1", "")); err != nil {
+		t.Fatal(err)
+	}
+	if err := apply("trx2", "bob", note("reply1", "missing")); err == nil {
+		t.Errorf("reply to a missing post accepted")
+	}
+	if err := apply("trx3", "bob", note("reply1", "reply1")); err == nil {
+		t.Errorf("reply to itself by object id accepted")
+	}
+	if err := apply("trx4", "bob", note("", "trx4")); err == nil {
+		t.Errorf("reply to itself by trx id accepted")
+	}
+	if err := apply("trx5", "bob", note("trx1", "")); err == nil {
+		t.Errorf("object id of the trx of another post accepted")
+	}
+	if err := apply("post1", "bob", note("", "")); err == nil {
+		t.Errorf("trx id of another object accepted")
+	}
+
+	//a reaction trx id does not refer to the post
+	if err := apply("trx6", "bob", &quorumpb.Activity{Type: "Like", Object: &quorumpb.Object{Id: "trx1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if index.ObjectExists("trx6") {
+		t.Errorf("reaction trx id refers to a post")
+	}
+	if err := apply("trx7", "bob", note("reply2", "trx6")); err == nil {
+		t.Errorf("reply to a reaction trx accepted")
+	}
+
+	profile := &quorumpb.Activity{Type: "Update", Person: &quorumpb.Person{Name: "alice"}}
+	if err := apply("trx8", "alice", profile); err != nil {
+		t.Fatal(err)
+	}
+	if err := apply("trx8", "alice", profile); err == nil {
+		t.Errorf("profile trx applied twice")
+	}
+
+	//a long chain of replies
+	parent := "post1"
+	for i := 0; i < 2000; i++ {
+		id := fmt.Sprintf("chain%d", i)
+		if err := apply("chaintrx"+id, "bob", note(id, parent)); err != nil {
+			t.Fatal(err)
+		}
+		parent = id
+	}
+	thread := index.Thread("post1")
+	if len(thread) != 2001 || thread[2000].ObjectId != "chain1999" {
+		t.Errorf("unexpected thread of %d posts", len(thread))
+	}
+}
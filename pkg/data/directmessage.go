@@ -0,0 +1,184 @@
+package data
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	guuid "github.com/google/uuid"
+	localcrypto "github.com/rumsystem/keystore/pkg/crypto"
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// EncryptPubkeyLookup gives the encrypt pubkey announced by a sign pubkey in a group
+type EncryptPubkeyLookup interface {
+	GetEncryptPubkey(groupId string, signPubkey string) (string, error)
+}
+
+// GetDirectMessageTrx creates a POST trx readable only by the recipients in the to and bto of content
+// (an Object, or an Activity and its object). A recipient id is an age encrypt pubkey, or a sign pubkey
+// resolved with the RecipientResolver of the factory when it implements EncryptPubkeyLookup.
+// The bto recipients are removed from the content, the sender is always a recipient.
+func (factory *TrxFactory) GetDirectMessageTrx(keyalias string, content proto.Message) (*quorumpb.Trx, error) {
+	ids, content, err := directMessageRecipients(content)
+	if err != nil {
+		return nil, err
+	}
+	encodedcontent, err := factory.encodePostContent(content)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make(map[string]bool)
+	if self := factory.groupItem.UserEncryptPubkey; self != "" {
+		recipients[self] = true
+	}
+	for _, id := range ids {
+		pubkey, err := factory.resolveEncryptPubkey(id)
+		if err != nil {
+			return nil, err
+		}
+		recipients[pubkey] = true
+	}
+	pubkeys := make([]string, 0, len(recipients))
+	for pubkey := range recipients {
+		pubkeys = append(pubkeys, pubkey)
+	}
+	sort.Strings(pubkeys)
+
+	nonce, err := factory.chainNonce.GetNextNouce(factory.groupItem.GroupId, factory.nodename)
+	if err != nil {
+		return nil, err
+	}
+	return CreateDirectMessageTrxByEthKey(factory.nodename, factory.version, factory.groupItem, int64(nonce), encodedcontent, keyalias, pubkeys)
+}
+
+func (factory *TrxFactory) resolveEncryptPubkey(id string) (string, error) {
+	if strings.HasPrefix(id, "age1") {
+		return id, nil
+	}
+	lookup, ok := factory.recipientResolver.(EncryptPubkeyLookup)
+	if !ok {
+		return "", fmt.Errorf("recipient %s is not an encrypt pubkey", id)
+	}
+	return lookup.GetEncryptPubkey(factory.groupId, id)
+}
+
+// returns the ids of the to and bto recipients, and a copy of content without bto
+func directMessageRecipients(content proto.Message) ([]string, proto.Message, error) {
+	content = proto.Clone(content)
+	var ids []string
+	collect := func(to []*quorumpb.Object, bto []*quorumpb.Object) {
+		for _, obj := range append(to, bto...) {
+			if obj.Id != "" {
+				ids = append(ids, obj.Id)
+			}
+		}
+	}
+	switch c := content.(type) {
+	case *quorumpb.Object:
+		collect(c.To, c.Bto)
+		c.Bto = nil
+	case *quorumpb.Activity:
+		collect(c.To, c.Bto)
+		c.Bto = nil
+		if c.Object != nil {
+			collect(c.Object.To, c.Object.Bto)
+			c.Object.Bto = nil
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported direct message content %T", content)
+	}
+	if len(ids) == 0 {
+		return nil, nil, fmt.Errorf("direct message must have to or bto recipients")
+	}
+	return ids, content, nil
+}
+
+// CreateDirectMessageTrxWithoutSign creates a POST trx with data encrypted by age to the recipients,
+// wrapped in a DirectMessageEnvelope. The envelope tells nothing about the recipients: the age header only
+// holds an ephemeral share per recipient, a node finds its messages by trying to decrypt them.
+func CreateDirectMessageTrxWithoutSign(nodename string, version string, groupItem *quorumpb.GroupItem, nonce int64, data []byte, recipients []string) (*quorumpb.Trx, []byte, error) {
+	var trx quorumpb.Trx
+	trx.TrxId = guuid.New().String()
+	trx.Type = quorumpb.TrxType_POST
+	trx.GroupId = groupItem.GroupId
+	trx.SenderPubkey = groupItem.UserSignPubkey
+	trx.Nonce = nonce
+	trx.DirectMessage = true
+
+	if len(recipients) == 0 {
+		return &trx, []byte(""), fmt.Errorf("direct message must have recipients")
+	}
+	ks := localcrypto.GetKeystore()
+	payload, err := ks.EncryptTo(recipients, data)
+	if err != nil {
+		return &trx, []byte(""), err
+	}
+	envelope := &quorumpb.DirectMessageEnvelope{Payload: payload}
+	trx.Data, err = proto.Marshal(envelope)
+	if err != nil {
+		return &trx, []byte(""), err
+	}
+	trx.Version = version
+
+	UpdateTrxTimeLimit(&trx)
+
+	encoded, err := proto.Marshal(&trx)
+	if err != nil {
+		return &trx, []byte(""), err
+	}
+	hashed := localcrypto.Hash(encoded)
+	return &trx, hashed, nil
+}
+
+func CreateDirectMessageTrxByEthKey(nodename string, version string, groupItem *quorumpb.GroupItem, nonce int64, data []byte, keyalias string, recipients []string) (*quorumpb.Trx, error) {
+	trx, hash, err := CreateDirectMessageTrxWithoutSign(nodename, version, groupItem, nonce, data, recipients)
+	if err != nil {
+		return trx, err
+	}
+	err = signTrx(trx, hash, groupItem, keyalias)
+	return trx, err
+}
+
+// IsDirectMessageTo tells whether a direct message trx can be decrypted with the encrypt key named by the
+// group id, or with the key of keyalias. It fails only for a trx which is not a valid direct message.
+func IsDirectMessageTo(trx *quorumpb.Trx, keyalias string) (bool, error) {
+	if _, err := directMessageEnvelope(trx); err != nil {
+		return false, err
+	}
+	_, err := DecryptDirectMessage(trx, keyalias)
+	return err == nil, nil
+}
+
+// DecryptDirectMessage decrypts the data of a direct message trx with the encrypt key named by the group id,
+// or with the key of keyalias
+func DecryptDirectMessage(trx *quorumpb.Trx, keyalias string) ([]byte, error) {
+	envelope, err := directMessageEnvelope(trx)
+	if err != nil {
+		return nil, err
+	}
+	ks := localcrypto.GetKeystore()
+	var data []byte
+	if keyalias == "" {
+		data, err = ks.Decrypt(trx.GroupId, envelope.Payload)
+	} else {
+		data, err = ks.DecryptByAlias(keyalias, envelope.Payload)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decrypt direct message %s failed, not a recipient: %s", trx.TrxId, err)
+	}
+	return data, nil
+}
+
+func directMessageEnvelope(trx *quorumpb.Trx) (*quorumpb.DirectMessageEnvelope, error) {
+	if !trx.DirectMessage {
+		return nil, fmt.Errorf("trx %s is not a direct message", trx.TrxId)
+	}
+	envelope := &quorumpb.DirectMessageEnvelope{}
+	if err := proto.Unmarshal(trx.Data, envelope); err != nil {
+		return nil, fmt.Errorf("invalid direct message envelope of trx %s: %s", trx.TrxId, err)
+	}
+	return envelope, nil
+}
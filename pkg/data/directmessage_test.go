@@ -0,0 +1,102 @@
+package data
+
+import (
+	"bytes"
+	"testing"
+
+	localcrypto "github.com/rumsystem/keystore/pkg/crypto"
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDirectMessage(t *testing.T) {
+	keystoreDir := t.TempDir()
+	tn := &TestNonce{}
+	trxFactory := &TrxFactory{}
+	groupitem := GetGroupItem()
+	trxFactory.Init("1.0.0", groupitem, "default", tn)
+	_, pubkey, err := GetKeyStorePubKey(groupitem.GroupId, keystoreDir)
+	if err != nil {
+		t.Fatalf("keystore new key err : %s", err)
+	}
+	groupitem.UserSignPubkey = pubkey
+	groupitem.OwnerPubKey = pubkey
+
+	ks := localcrypto.GetKeystore().(*localcrypto.DirKeyStore)
+	if _, err := ks.NewKeyWithDefaultPassword(groupitem.GroupId, localcrypto.Encrypt); err != nil {
+		t.Fatalf("keystore new encrypt key err : %s", err)
+	}
+	encryptpubkey, err := ks.GetEncodedPubkey(groupitem.GroupId, localcrypto.Encrypt)
+	if err != nil {
+		t.Fatalf("get encrypt pubkey err : %s", err)
+	}
+	otherkeyname := groupitem.GroupId + "_other"
+	if _, err := ks.NewKeyWithDefaultPassword(otherkeyname, localcrypto.Encrypt); err != nil {
+		t.Fatalf("keystore new encrypt key err : %s", err)
+	}
+	otherpubkey, err := ks.GetEncodedPubkey(otherkeyname, localcrypto.Encrypt)
+	if err != nil {
+		t.Fatalf("get encrypt pubkey err : %s", err)
+	}
+
+	if _, err := trxFactory.GetDirectMessageTrx("", &quorumpb.Object{Type: "Note", Content: "to nobody"}); err == nil {
+		t.Errorf("direct message without recipients accepted")
+	}
+
+	//the local node is not a recipient
+	obj := &quorumpb.Object{Type: "Note", Content: "secret", To: []*quorumpb.Object{{Id: otherpubkey}}}
+	trx, err := trxFactory.GetDirectMessageTrx("", obj)
+	if err != nil {
+		t.Fatalf("create direct message err: %s", err)
+	}
+	if ok, err := VerifyTrx(trx); !ok {
+		t.Fatalf("verify direct message err: %v", err)
+	}
+	if bytes.Contains(trx.Data, []byte("secret")) {
+		t.Errorf("direct message content not encrypted")
+	}
+	if ok, _ := IsDirectMessageTo(trx, ""); ok {
+		t.Errorf("direct message detected by a non recipient")
+	}
+	if _, err := DecryptDirectMessage(trx, ""); err == nil {
+		t.Errorf("direct message decrypted by a non recipient")
+	}
+
+	//a recipient given by its sign pubkey is resolved with the membership, bto is not disclosed
+	state := NewMembershipState(groupitem.GroupId, pubkey)
+	state.ApplyAnnounceItem(1, "member", &quorumpb.AnnounceItem{GroupId: groupitem.GroupId, SignPubkey: "member", EncryptPubkey: encryptpubkey, Type: quorumpb.AnnounceType_AS_USER})
+	trxFactory.SetRecipientResolver(state)
+	obj = &quorumpb.Object{Type: "Note", Content: "secret", Bto: []*quorumpb.Object{{Id: "member"}}}
+	trx, err = trxFactory.GetDirectMessageTrx("", obj)
+	if err != nil {
+		t.Fatalf("create direct message err: %s", err)
+	}
+	if ok, _ := IsDirectMessageTo(trx, ""); !ok {
+		t.Errorf("direct message not detected by its recipient")
+	}
+	//the trx discloses nothing about the bto recipient
+	envelope := &quorumpb.DirectMessageEnvelope{}
+	if err := proto.Unmarshal(trx.Data, envelope); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(envelope, &quorumpb.DirectMessageEnvelope{Payload: envelope.Payload}) || bytes.Contains(trx.Data, []byte(encryptpubkey)) {
+		t.Errorf("direct message envelope discloses its recipients")
+	}
+	data, err := DecryptDirectMessage(trx, "")
+	if err != nil {
+		t.Fatalf("decrypt direct message err: %s", err)
+	}
+	content, _, err := DecodePostContent(trx.TrxId, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := content.(*quorumpb.Object)
+	if decoded.Content != "secret" || len(decoded.Bto) != 0 {
+		t.Errorf("unexpected direct message content: %v", decoded)
+	}
+
+	trx.DirectMessage = false
+	if ok, _ := VerifyTrx(trx); ok {
+		t.Errorf("trx with a modified direct message flag verified")
+	}
+}
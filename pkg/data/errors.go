@@ -0,0 +1,55 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors of the verification of trxs and blocks, test them with errors.Is.
+//
+// ErrHashMismatch, ErrBadSignature, ErrUnknownKeyFormat and ErrOversize mean the data itself is invalid,
+// the peer which sent it can be banned, see IsInvalidData. ErrPrevHashMismatch, ErrExpired and
+// ErrIneligibleProducer may come from a fork, an out of order sync, a late delivery or a node behind on
+// the producer schedule: the data can be requested again.
+var (
+	ErrHashMismatch     = errors.New("hash mismatch")
+	ErrPrevHashMismatch = errors.New("previous hash mismatch")
+	ErrBadSignature     = errors.New("bad signature")
+	ErrUnknownKeyFormat = errors.New("unknown key format")
+	ErrExpired          = errors.New("expired")
+	ErrOversize         = errors.New("oversize")
+
+	ErrIneligibleProducer = errors.New("producer not eligible")
+)
+
+// VerifyError is the error of the verification of a trx, a block or a group seed, Err wraps one of the sentinel errors
+type VerifyError struct {
+	Kind string //"trx", "block" or "seed"
+	Id   string
+	Err  error
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Kind, e.Id, e.Err)
+}
+
+func (e *VerifyError) Unwrap() error {
+	return e.Err
+}
+
+func trxVerifyError(trxid string, err error) error {
+	return &VerifyError{Kind: "trx", Id: trxid, Err: err}
+}
+
+func blockVerifyError(blockid string, err error) error {
+	return &VerifyError{Kind: "block", Id: blockid, Err: err}
+}
+
+func seedVerifyError(groupid string, err error) error {
+	return &VerifyError{Kind: "seed", Id: groupid, Err: err}
+}
+
+// IsInvalidData tells whether err is caused by invalid data, as opposed to data out of date or out of order
+func IsInvalidData(err error) bool {
+	return errors.Is(err, ErrHashMismatch) || errors.Is(err, ErrBadSignature) || errors.Is(err, ErrUnknownKeyFormat) || errors.Is(err, ErrOversize)
+}
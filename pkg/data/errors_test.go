@@ -0,0 +1,73 @@
+package data
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestVerifyErrors(t *testing.T) {
+	_, blocks := getTestChain(t, 2)
+
+	check := func(name string, ok bool, err error, expect error, invalid bool) {
+		if ok || !errors.Is(err, expect) {
+			t.Errorf("%s: got %v, %v, expect %v", name, ok, err, expect)
+			return
+		}
+		var verifyerr *VerifyError
+		if !errors.As(err, &verifyerr) || verifyerr.Id == "" {
+			t.Errorf("%s: %v is not a VerifyError", name, err)
+		}
+		if IsInvalidData(err) != invalid {
+			t.Errorf("%s: invalid data %v, expect %v", name, !invalid, invalid)
+		}
+	}
+
+	block := proto.Clone(blocks[1]).(*quorumpb.Block)
+	block.TimeStamp++
+	ok, err := IsBlockValid(block, blocks[0])
+	check("tampered block", ok, err, ErrHashMismatch, true)
+
+	ok, err = IsBlockValid(blocks[0], blocks[1])
+	check("block out of order", ok, err, ErrPrevHashMismatch, false)
+
+	block = proto.Clone(blocks[1]).(*quorumpb.Block)
+	block.Signature = blocks[0].Signature
+	ok, err = VerifyBlockSign(block)
+	check("block signed by another block", ok, err, ErrBadSignature, true)
+
+	block.Signature = nil
+	ok, err = VerifyBlockSign(block)
+	check("unsigned block", ok, err, ErrBadSignature, true)
+
+	trx := getTestTrxs(t, 1)[0]
+	trx.SenderPubkey = "not a pubkey"
+	ok, err = VerifyTrx(trx)
+	check("unknown pubkey", ok, err, ErrUnknownKeyFormat, true)
+
+	err = VerifyTrxExpiry(trx, time.Unix(0, trx.Expired).Add(time.Second))
+	check("expired trx", false, err, ErrExpired, false)
+	if err := VerifyTrxExpiry(trx, time.Unix(0, trx.TimeStamp)); err != nil {
+		t.Errorf("trx expired at its timestamp: %s", err)
+	}
+
+	//a valid result has no error
+	if ok, err := VerifyBlockSign(blocks[0]); !ok || err != nil {
+		t.Errorf("valid block: %v, %v", ok, err)
+	}
+}
+
+func TestOversizeContent(t *testing.T) {
+	trxFactory := &TrxFactory{}
+	groupitem := GetGroupItem()
+	trxFactory.Init("1.0.0", groupitem, "default", &TestNonce{})
+	obj := &quorumpb.Object{Type: "Note", Content: strings.Repeat("x", OBJECT_SIZE_LIMIT*2)}
+	_, err := trxFactory.encodePostContent(obj)
+	if !errors.Is(err, ErrOversize) || !IsInvalidData(err) {
+		t.Errorf("oversize content: %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+
+	guuid "github.com/google/uuid"
+	localcrypto "github.com/rumsystem/keystore/pkg/crypto"
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	FILE_CHUNK_SIZE = 150 * 1024        //(150Kb) a chunk trx stays under OBJECT_SIZE_LIMIT
+	FILE_SIZE_LIMIT = 100 * 1024 * 1024 //(100Mb)
+
+	FILE_PENDING_CHUNKS = (FILE_SIZE_LIMIT + FILE_CHUNK_SIZE - 1) / FILE_CHUNK_SIZE //chunks of a file of FILE_SIZE_LIMIT bytes
+)
+
+// SplitFile compresses the content of file according to file.Compression and splits it into chunks
+// of chunkSize bytes, the manifest carries the hash of each chunk and of the whole content
+func SplitFile(file *quorumpb.File, chunkSize int) (*quorumpb.FileManifest, []*quorumpb.FileChunk, error) {
+	if chunkSize <= 0 || chunkSize > FILE_CHUNK_SIZE {
+		return nil, nil, fmt.Errorf("chunk size must be in (0, %d]", FILE_CHUNK_SIZE)
+	}
+	if len(file.Content) == 0 {
+		return nil, nil, fmt.Errorf("file has no content")
+	}
+	if len(file.Content) > FILE_SIZE_LIMIT {
+		return nil, nil, fmt.Errorf("%w: file size over %d bytes", ErrOversize, FILE_SIZE_LIMIT)
+	}
+	compressed, err := compressBytes(file.Compression, file.Content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fileId := file.Id
+	if fileId == "" {
+		fileId = guuid.New().String()
+	}
+	manifest := &quorumpb.FileManifest{
+		Id:          fileId,
+		Name:        file.Name,
+		MediaType:   file.MediaType,
+		Compression: file.Compression,
+		Size:        int64(len(file.Content)),
+		Hash:        localcrypto.Hash(file.Content),
+		Url:         file.Url,
+	}
+	var chunks []*quorumpb.FileChunk
+	for offset := 0; offset < len(compressed); offset += chunkSize {
+		end := offset + chunkSize
+		if end > len(compressed) {
+			end = len(compressed)
+		}
+		index := int32(len(chunks))
+		content := compressed[offset:end]
+		chunks = append(chunks, &quorumpb.FileChunk{Fileid: fileId, Index: index, Content: content})
+		manifest.Chunks = append(manifest.Chunks, &quorumpb.FileChunkInfo{Index: index, Size: int64(len(content)), Hash: localcrypto.Hash(content)})
+	}
+	return manifest, chunks, nil
+}
+
+// AssembleFile verifies the chunks against the manifest and returns the decompressed file
+func AssembleFile(manifest *quorumpb.FileManifest, chunks []*quorumpb.FileChunk) (*quorumpb.File, error) {
+	if len(chunks) != len(manifest.Chunks) {
+		return nil, fmt.Errorf("file %s has %d chunks, expect %d", manifest.Id, len(chunks), len(manifest.Chunks))
+	}
+	if manifest.Size <= 0 || manifest.Size > FILE_SIZE_LIMIT {
+		return nil, fmt.Errorf("invalid size %d of file %s", manifest.Size, manifest.Id)
+	}
+	sorted := make([]*quorumpb.FileChunk, len(chunks))
+	copy(sorted, chunks)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Index < sorted[j].Index })
+
+	var compressed bytes.Buffer
+	for i, chunk := range sorted {
+		info := manifest.Chunks[i]
+		if chunk.Fileid != manifest.Id || chunk.Index != int32(i) || info.Index != int32(i) {
+			return nil, fmt.Errorf("chunk %d of file %s is missing", i, manifest.Id)
+		}
+		if int64(len(chunk.Content)) != info.Size || !bytes.Equal(localcrypto.Hash(chunk.Content), info.Hash) {
+			return nil, fmt.Errorf("chunk %d of file %s does not match the manifest", i, manifest.Id)
+		}
+		compressed.Write(chunk.Content)
+	}
+
+	content, err := decompressBytes(manifest.Compression, compressed.Bytes(), manifest.Size)
+	if err != nil {
+		return nil, fmt.Errorf("decompress file %s failed: %s", manifest.Id, err)
+	}
+	if int64(len(content)) != manifest.Size || !bytes.Equal(localcrypto.Hash(content), manifest.Hash) {
+		return nil, fmt.Errorf("content of file %s does not match the manifest", manifest.Id)
+	}
+	return &quorumpb.File{
+		Id:        manifest.Id,
+		Name:      manifest.Name,
+		MediaType: manifest.MediaType,
+		Content:   content,
+		Url:       manifest.Url,
+	}, nil
+}
+
+// GetFileTrxs splits file with SplitFile and returns one POST trx per chunk followed by the manifest POST trx
+func (factory *TrxFactory) GetFileTrxs(keyalias string, file *quorumpb.File, encryptto ...[]string) ([]*quorumpb.Trx, error) {
+	manifest, chunks, err := SplitFile(file, FILE_CHUNK_SIZE)
+	if err != nil {
+		return nil, err
+	}
+	var trxs []*quorumpb.Trx
+	for _, chunk := range chunks {
+		trx, err := factory.GetPostAnyTrx(keyalias, chunk, encryptto...)
+		if err != nil {
+			return nil, err
+		}
+		trxs = append(trxs, trx)
+	}
+	trx, err := factory.GetPostAnyTrx(keyalias, manifest, encryptto...)
+	if err != nil {
+		return nil, err
+	}
+	return append(trxs, trx), nil
+}
+
+// FileAssembler collects the decoded FileChunk and FileManifest POST contents of files,
+// in any order, and assembles each file once all its chunks are received. Files are kept apart by
+// sender, so a sender can not complete or spoil the file of another. A chunk is checked against the
+// manifest as soon as both are received, the pending chunks of a file are bounded by
+// FILE_PENDING_CHUNKS chunks and FILE_SIZE_LIMIT bytes whether its manifest is received or not.
+type FileAssembler struct {
+	mu    sync.Mutex
+	files map[fileKey]*pendingFile
+}
+
+type fileKey struct {
+	Sender string
+	FileId string
+}
+
+type pendingFile struct {
+	manifest *quorumpb.FileManifest
+	chunks   map[int32]*quorumpb.FileChunk
+	size     int64 //bytes of the chunks
+}
+
+func NewFileAssembler() *FileAssembler {
+	return &FileAssembler{files: make(map[fileKey]*pendingFile)}
+}
+
+// Add collects a content of sender, it returns the assembled file when content completes it.
+// A chunk or a manifest which does not match the other contents of the file is rejected with an error,
+// the contents already collected are kept. Contents which are not a FileChunk or a FileManifest are ignored.
+func (assembler *FileAssembler) Add(sender string, content proto.Message) (*quorumpb.File, error) {
+	assembler.mu.Lock()
+	defer assembler.mu.Unlock()
+
+	var key fileKey
+	switch c := content.(type) {
+	case *quorumpb.FileManifest:
+		key = fileKey{Sender: sender, FileId: c.Id}
+		if err := assembler.addManifest(key, c); err != nil {
+			return nil, err
+		}
+	case *quorumpb.FileChunk:
+		key = fileKey{Sender: sender, FileId: c.Fileid}
+		if err := assembler.addChunk(key, c); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+
+	file := assembler.files[key]
+	if file.manifest == nil || len(file.chunks) < len(file.manifest.Chunks) {
+		return nil, nil
+	}
+	chunks := make([]*quorumpb.FileChunk, 0, len(file.chunks))
+	for _, chunk := range file.chunks {
+		chunks = append(chunks, chunk)
+	}
+	delete(assembler.files, key)
+	return AssembleFile(file.manifest, chunks)
+}
+
+// must be called with assembler.mu locked
+func (assembler *FileAssembler) pending(key fileKey) *pendingFile {
+	file, ok := assembler.files[key]
+	if !ok {
+		file = &pendingFile{chunks: make(map[int32]*quorumpb.FileChunk)}
+		assembler.files[key] = file
+	}
+	return file
+}
+
+// must be called with assembler.mu locked
+func (assembler *FileAssembler) addManifest(key fileKey, manifest *quorumpb.FileManifest) error {
+	if manifest.Size <= 0 || manifest.Size > FILE_SIZE_LIMIT {
+		return fmt.Errorf("invalid size %d of file %s", manifest.Size, manifest.Id)
+	}
+	if len(manifest.Chunks) == 0 || len(manifest.Chunks) > FILE_PENDING_CHUNKS {
+		return fmt.Errorf("invalid chunk count %d of file %s", len(manifest.Chunks), manifest.Id)
+	}
+	for i, info := range manifest.Chunks {
+		if info.Index != int32(i) || info.Size <= 0 || info.Size > FILE_CHUNK_SIZE {
+			return fmt.Errorf("invalid chunk %d of file %s in the manifest", i, manifest.Id)
+		}
+	}
+	file := assembler.pending(key)
+	if file.manifest != nil {
+		if proto.Equal(file.manifest, manifest) {
+			return nil
+		}
+		return fmt.Errorf("file %s of %s already has another manifest", manifest.Id, key.Sender)
+	}
+	file.manifest = manifest
+	//drop the chunks received before the manifest which do not match it
+	var mismatched []int32
+	for index, chunk := range file.chunks {
+		if !chunkMatches(manifest, chunk) {
+			delete(file.chunks, index)
+			file.size -= int64(len(chunk.Content))
+			mismatched = append(mismatched, index)
+		}
+	}
+	if len(mismatched) > 0 {
+		sort.Slice(mismatched, func(i, j int) bool { return mismatched[i] < mismatched[j] })
+		return fmt.Errorf("chunks %v of file %s do not match the manifest", mismatched, manifest.Id)
+	}
+	return nil
+}
+
+// must be called with assembler.mu locked
+func (assembler *FileAssembler) addChunk(key fileKey, chunk *quorumpb.FileChunk) error {
+	if chunk.Index < 0 || len(chunk.Content) == 0 || len(chunk.Content) > FILE_CHUNK_SIZE {
+		return fmt.Errorf("invalid chunk %d of file %s", chunk.Index, chunk.Fileid)
+	}
+	file := assembler.pending(key)
+	if file.manifest != nil && !chunkMatches(file.manifest, chunk) {
+		return fmt.Errorf("chunk %d of file %s does not match the manifest", chunk.Index, chunk.Fileid)
+	}
+	if received, ok := file.chunks[chunk.Index]; ok {
+		if bytes.Equal(received.Content, chunk.Content) {
+			return nil
+		}
+		return fmt.Errorf("chunk %d of file %s already received with another content", chunk.Index, chunk.Fileid)
+	}
+	if len(file.chunks) >= FILE_PENDING_CHUNKS || file.size+int64(len(chunk.Content)) > FILE_SIZE_LIMIT {
+		return fmt.Errorf("%w: pending chunks of file %s over %d chunks or %d bytes", ErrOversize, chunk.Fileid, FILE_PENDING_CHUNKS, FILE_SIZE_LIMIT)
+	}
+	file.chunks[chunk.Index] = chunk
+	file.size += int64(len(chunk.Content))
+	return nil
+}
+
+func chunkMatches(manifest *quorumpb.FileManifest, chunk *quorumpb.FileChunk) bool {
+	if int(chunk.Index) >= len(manifest.Chunks) {
+		return false
+	}
+	info := manifest.Chunks[chunk.Index]
+	return int64(len(chunk.Content)) == info.Size && bytes.Equal(localcrypto.Hash(chunk.Content), info.Hash)
+}
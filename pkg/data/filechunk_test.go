@@ -0,0 +1,169 @@
+package data
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+)
+
+func getTestFileContent(size int) []byte {
+	r := rand.New(rand.NewSource(1))
+	content := make([]byte, size)
+	r.Read(content[:size/2]) //half random, half compressible
+	return content
+}
+
+func TestSplitAndAssembleFile(t *testing.T) {
+	content := getTestFileContent(500 * 1024)
+	for _, compression := range []quorumpb.File_Compression{quorumpb.File_none, quorumpb.File_gz, quorumpb.File_zip} {
+		file := &quorumpb.File{Name: "test.bin", MediaType: "application/octet-stream", Compression: compression, Content: content}
+		manifest, chunks, err := SplitFile(file, FILE_CHUNK_SIZE)
+		if err != nil {
+			t.Fatalf("split %s file err: %s", compression, err)
+		}
+		if len(chunks) < 2 {
+			t.Errorf("%s file should have several chunks, got %d", compression, len(chunks))
+		}
+
+		//chunks can arrive in any order
+		chunks[0], chunks[len(chunks)-1] = chunks[len(chunks)-1], chunks[0]
+		assembled, err := AssembleFile(manifest, chunks)
+		if err != nil {
+			t.Fatalf("assemble %s file err: %s", compression, err)
+		}
+		if !bytes.Equal(assembled.Content, content) || assembled.Name != file.Name {
+			t.Errorf("assembled %s file mismatch", compression)
+		}
+
+		chunks[1].Content = append([]byte{}, chunks[1].Content...)
+		chunks[1].Content[0] ^= 0xff
+		if _, err := AssembleFile(manifest, chunks); err == nil {
+			t.Errorf("tampered %s chunk accepted", compression)
+		}
+		if _, err := AssembleFile(manifest, chunks[1:]); err == nil {
+			t.Errorf("%s file with a missing chunk accepted", compression)
+		}
+	}
+}
+
+func TestGetFileTrxs(t *testing.T) {
+	keystoreDir := t.TempDir()
+	tn := &TestNonce{}
+	trxFactory := &TrxFactory{}
+	groupitem := GetGroupItem()
+	trxFactory.Init("1.0.0", groupitem, "default", tn)
+	_, pubkey, err := GetKeyStorePubKey(groupitem.GroupId, keystoreDir)
+	if err != nil {
+		t.Fatalf("keystore new key err : %s", err)
+	}
+	groupitem.UserSignPubkey = pubkey
+
+	content := getTestFileContent(3 * OBJECT_SIZE_LIMIT)
+	file := &quorumpb.File{Name: "test.pdf", MediaType: "application/pdf", Compression: quorumpb.File_gz, Content: content}
+	trxs, err := trxFactory.GetFileTrxs("", file)
+	if err != nil {
+		t.Fatalf("create file trxs err: %s", err)
+	}
+
+	ring := NewCipherKeyRing(groupitem)
+	assembler := NewFileAssembler()
+	var assembled *quorumpb.File
+	//receive the manifest first
+	for _, trx := range append(trxs[len(trxs)-1:], trxs[:len(trxs)-1]...) {
+		if ok, err := VerifyTrx(trx); !ok {
+			t.Fatalf("verify file trx err: %v", err)
+		}
+		data, err := ring.DecryptTrx(trx)
+		if err != nil {
+			t.Fatalf("decrypt file trx err: %s", err)
+		}
+		msg, _, err := quorumpb.BytesToMessage(trx.TrxId, data)
+		if err != nil {
+			t.Fatalf("decode file trx err: %s", err)
+		}
+		f, err := assembler.Add(trx.SenderPubkey, msg)
+		if err != nil {
+			t.Fatalf("assemble file err: %s", err)
+		}
+		if f != nil {
+			assembled = f
+		}
+	}
+	if assembled == nil || !bytes.Equal(assembled.Content, content) {
+		t.Errorf("file not assembled from trxs")
+	}
+}
+
+func TestFileAssembler(t *testing.T) {
+	content := getTestFileContent(4 * 1024)
+	manifest, chunks, err := SplitFile(&quorumpb.File{Id: "file1", Name: "test.bin", Content: content}, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := &quorumpb.FileChunk{Fileid: "file1", Index: 0, Content: bytes.Repeat([]byte{1}, len(chunks[0].Content))}
+
+	//chunks of another sender do not complete the file
+	assembler := NewFileAssembler()
+	if _, err := assembler.Add("alice", manifest); err != nil {
+		t.Fatal(err)
+	}
+	for _, chunk := range chunks {
+		if f, err := assembler.Add("mallory", chunk); err != nil || f != nil {
+			t.Fatalf("chunk of another sender got file %v, err %v", f, err)
+		}
+	}
+
+	//a chunk which does not match the manifest is rejected, the file is still assembled
+	if _, err := assembler.Add("alice", forged); err == nil {
+		t.Errorf("chunk not matching the manifest accepted")
+	}
+	var assembled *quorumpb.File
+	for _, chunk := range chunks {
+		f, err := assembler.Add("alice", chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f != nil {
+			assembled = f
+		}
+	}
+	if assembled == nil || !bytes.Equal(assembled.Content, content) {
+		t.Errorf("file not assembled after a rejected chunk")
+	}
+
+	//a chunk received before the manifest is dropped when it does not match it
+	assembler = NewFileAssembler()
+	if _, err := assembler.Add("alice", forged); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := assembler.Add("alice", manifest); err == nil {
+		t.Errorf("mismatched chunk received before the manifest not reported")
+	}
+	assembled = nil
+	for _, chunk := range chunks {
+		f, err := assembler.Add("alice", chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f != nil {
+			assembled = f
+		}
+	}
+	if assembled == nil || !bytes.Equal(assembled.Content, content) {
+		t.Errorf("file not assembled after a dropped chunk")
+	}
+
+	//the chunks of a file without manifest are bounded
+	assembler = NewFileAssembler()
+	for i := 0; i < FILE_PENDING_CHUNKS; i++ {
+		if _, err := assembler.Add("mallory", &quorumpb.FileChunk{Fileid: "file2", Index: int32(i), Content: []byte{1}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := assembler.Add("mallory", &quorumpb.FileChunk{Fileid: "file2", Index: FILE_PENDING_CHUNKS, Content: []byte{1}}); !errors.Is(err, ErrOversize) {
+		t.Errorf("chunk over the pending limit got err %v", err)
+	}
+}
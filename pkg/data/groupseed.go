@@ -0,0 +1,114 @@
+package data
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
+	localcrypto "github.com/rumsystem/keystore/pkg/crypto"
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+)
+
+// GroupSeedHash returns the hash signed by the group owner, the scheme of the quorum node when it creates a
+// group and checks a seed to join it: the hash of the JSON encoded GenesisBlock, GroupId, GroupName, the
+// decoded OwnerPubkey, ConsensusType, EncryptionType, CipherKey and AppKey, concatenated in this order
+func GroupSeedHash(seed *quorumpb.GroupSeed) ([]byte, error) {
+	genesis, err := json.Marshal(seed.GenesisBlock)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := decodePubkey(seed.OwnerPubkey)
+	if err != nil {
+		return nil, seedVerifyError(seed.GroupId, err)
+	}
+	var buffer bytes.Buffer
+	buffer.Write(genesis)
+	buffer.Write([]byte(seed.GroupId))
+	buffer.Write([]byte(seed.GroupName))
+	buffer.Write(owner)
+	buffer.Write([]byte(seed.ConsensusType))
+	buffer.Write([]byte(seed.EncryptionType))
+	buffer.Write([]byte(seed.CipherKey))
+	buffer.Write([]byte(seed.AppKey))
+	return localcrypto.Hash(buffer.Bytes()), nil
+}
+
+// decodePubkey returns the bytes of an encoded eth or libp2p pubkey, decoded as ParsePubkey does
+func decodePubkey(s string) ([]byte, error) {
+	if bytespubkey, err := base64.RawURLEncoding.DecodeString(s); err == nil {
+		if _, err := ethcrypto.DecompressPubkey(bytespubkey); err == nil {
+			return bytespubkey, nil
+		}
+	}
+	serializedpub, err := p2pcrypto.ConfigDecodeKey(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyFormat, err)
+	}
+	return serializedpub, nil
+}
+
+// SignGroupSeed sets the hex encoded Signature of seed with the key named by the group id, or with the key of keyalias
+func SignGroupSeed(seed *quorumpb.GroupSeed, keystore localcrypto.Keystore, keyalias string) error {
+	hash, err := GroupSeedHash(seed)
+	if err != nil {
+		return err
+	}
+	var signature []byte
+	if keyalias == "" {
+		signature, err = keystore.EthSignByKeyName(seed.GroupId, hash)
+	} else {
+		signature, err = keystore.EthSignByKeyAlias(keyalias, hash)
+	}
+	if err != nil {
+		return err
+	}
+	if len(signature) == 0 {
+		return errors.New("create signature on group seed failed")
+	}
+	seed.Signature = hex.EncodeToString(signature)
+	return nil
+}
+
+// VerifyGroupSeed checks the genesis block of seed, which must be produced by the group owner, and the
+// signature of seed by the group owner
+func VerifyGroupSeed(seed *quorumpb.GroupSeed) (bool, error) {
+	genesis := seed.GenesisBlock
+	if genesis == nil || genesis.GroupId != seed.GroupId || genesis.PrevBlockId != "" {
+		return false, fmt.Errorf("invalid genesis block in seed of group %s", seed.GroupId)
+	}
+	hash, err := BlockHash(genesis)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(hash, genesis.Hash) {
+		return false, blockVerifyError(genesis.BlockId, ErrHashMismatch)
+	}
+	if ok, err := VerifyBlockSign(genesis); !ok || err != nil {
+		return false, err
+	}
+	if genesis.ProducerPubKey != seed.OwnerPubkey {
+		return false, blockVerifyError(genesis.BlockId, fmt.Errorf("%w: genesis block produced by %s, expect group owner %s", ErrIneligibleProducer, genesis.ProducerPubKey, seed.OwnerPubkey))
+	}
+
+	signature, err := hex.DecodeString(seed.Signature)
+	if err != nil {
+		return false, seedVerifyError(seed.GroupId, fmt.Errorf("%w: %s", ErrBadSignature, err))
+	}
+	seedhash, err := GroupSeedHash(seed)
+	if err != nil {
+		return false, err
+	}
+	pubkey, err := DefaultPubkeyResolver.Resolve(seed.OwnerPubkey)
+	if err != nil {
+		return false, seedVerifyError(seed.GroupId, err)
+	}
+	if ok, err := pubkey.Verify(seedhash, signature); !ok {
+		return false, seedVerifyError(seed.GroupId, err)
+	}
+	return true, nil
+}
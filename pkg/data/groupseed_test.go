@@ -0,0 +1,50 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// a seed in the JSON format of the quorum node, signed over the concatenated fields
+const testNodeGroupSeed = `{"app_key":"test_app","cipher_key":"076a3cee50f3951744fbe6d973a853171139689fb48554b89f7765c0c6cbf15a","consensus_type":"poa","encryption_type":"public","genesis_block":{"BlockId":"80e3dbd6-24de-46cd-9290-ed2ae93ec3ac","GroupId":"5ed3f9fe-81e2-450d-9146-7a329aac2b62","ProducerPubKey":"Ak47ga-cIjTK0J1nnOYDXtE5I0fOZM5AX13NNiKKJd5u","Hash":"j3CsLTmBl2Fab0W8oIiMCeY/a5iMSgfm7HWqsfcmdm0=","Signature":"IoE5Qyp3F3hfiIN5vFnKW5KjmnhGAuxK8W/QeY9Yot9Tmic3OyO66fNS3p3GfJ+lUhkRqQSUy23iI5YRYMv3vwA=","TimeStamp":"1651145212000000000"},"group_id":"5ed3f9fe-81e2-450d-9146-7a329aac2b62","group_name":"my_test_group","owner_pubkey":"Ak47ga-cIjTK0J1nnOYDXtE5I0fOZM5AX13NNiKKJd5u","signature":"24bc2ca357a84954b97fb3561572ef5ea83b70ac0df71ad58662c7a599e364da1da0f8aa354d9651b7f39926190fbc3c2768f49132ac09bad8af602b081add0900"}`
+
+func TestVerifyNodeGroupSeed(t *testing.T) {
+	var nodeseed struct {
+		GenesisBlock   *quorumpb.Block `json:"genesis_block"`
+		GroupId        string          `json:"group_id"`
+		GroupName      string          `json:"group_name"`
+		OwnerPubkey    string          `json:"owner_pubkey"`
+		ConsensusType  string          `json:"consensus_type"`
+		EncryptionType string          `json:"encryption_type"`
+		CipherKey      string          `json:"cipher_key"`
+		AppKey         string          `json:"app_key"`
+		Signature      string          `json:"signature"`
+	}
+	if err := json.Unmarshal([]byte(testNodeGroupSeed), &nodeseed); err != nil {
+		t.Fatal(err)
+	}
+	seed := &quorumpb.GroupSeed{
+		GenesisBlock:   nodeseed.GenesisBlock,
+		GroupId:        nodeseed.GroupId,
+		GroupName:      nodeseed.GroupName,
+		OwnerPubkey:    nodeseed.OwnerPubkey,
+		ConsensusType:  nodeseed.ConsensusType,
+		EncryptionType: nodeseed.EncryptionType,
+		CipherKey:      nodeseed.CipherKey,
+		AppKey:         nodeseed.AppKey,
+		Signature:      nodeseed.Signature,
+	}
+	if ok, err := VerifyGroupSeed(seed); !ok || err != nil {
+		t.Fatalf("verify seed of the node: %v", err)
+	}
+
+	forged := proto.Clone(seed).(*quorumpb.GroupSeed)
+	forged.AppKey = "forged_app"
+	if _, err := VerifyGroupSeed(forged); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("verify forged seed: got %v, expect %s", err, ErrBadSignature)
+	}
+}
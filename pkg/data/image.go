@@ -0,0 +1,252 @@
+package data
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"strings"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+)
+
+const (
+	THUMBNAIL_SIZE = 256 //max width and height of a preview thumbnail
+
+	//decoding a larger image is refused
+	IMAGE_PIXELS_LIMIT = 50 * 1000 * 1000
+)
+
+var (
+	pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+	//png chunks which carry metadata
+	pngMetadataChunks = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+)
+
+// ImageMediaType returns the media type sniffed from the content of an image
+func ImageMediaType(content []byte) string {
+	mediaType := http.DetectContentType(content)
+	if i := strings.Index(mediaType, ";"); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	return mediaType
+}
+
+// ValidateImage checks the content of img is an image of its declared mediaType
+func ValidateImage(img *quorumpb.Image) error {
+	if len(img.Content) == 0 {
+		return fmt.Errorf("image %s has no content", img.Name)
+	}
+	sniffed := ImageMediaType(img.Content)
+	if !strings.HasPrefix(sniffed, "image/") {
+		return fmt.Errorf("content of image %s is %s, not an image", img.Name, sniffed)
+	}
+	declared := strings.ToLower(strings.TrimSpace(img.MediaType))
+	if declared == "image/jpg" {
+		declared = "image/jpeg"
+	}
+	if declared != sniffed {
+		return fmt.Errorf("image %s is declared as %s but its content is %s", img.Name, img.MediaType, sniffed)
+	}
+	return nil
+}
+
+// StripImageMetadata removes the EXIF, XMP and text metadata of a JPEG or PNG image, without re-encoding it.
+// Other formats are kept as is.
+func StripImageMetadata(img *quorumpb.Image) error {
+	var stripped []byte
+	var err error
+	switch ImageMediaType(img.Content) {
+	case "image/jpeg":
+		stripped, err = stripJPEGMetadata(img.Content)
+	case "image/png":
+		stripped, err = stripPNGMetadata(img.Content)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("strip metadata of image %s failed: %s", img.Name, err)
+	}
+	img.Content = stripped
+	return nil
+}
+
+// ImageThumbnail returns a copy of img downscaled to fit in maxSize x maxSize, a JPEG stays a JPEG,
+// other formats become PNG. An image already small enough is returned re-encoded, so without metadata.
+func ImageThumbnail(img *quorumpb.Image, maxSize int) (*quorumpb.Image, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("thumbnail size must be positive")
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(img.Content))
+	if err != nil {
+		return nil, fmt.Errorf("decode image %s failed: %s", img.Name, err)
+	}
+	if config.Width*config.Height > IMAGE_PIXELS_LIMIT {
+		return nil, fmt.Errorf("%w: image %s is over %d pixels", ErrOversize, img.Name, IMAGE_PIXELS_LIMIT)
+	}
+	src, _, err := image.Decode(bytes.NewReader(img.Content))
+	if err != nil {
+		return nil, fmt.Errorf("decode image %s failed: %s", img.Name, err)
+	}
+
+	thumb := downscaleImage(src, maxSize)
+	var buf bytes.Buffer
+	mediaType := "image/png"
+	if format == "jpeg" {
+		mediaType = "image/jpeg"
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&buf, thumb)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &quorumpb.Image{Name: img.Name, MediaType: mediaType, Content: buf.Bytes()}, nil
+}
+
+// PrepareImageObject validates the images of obj and strips their metadata, then sets obj.Preview to a
+// thumbnail of the first image if obj has no preview yet. It is meant to be called before GetPostAnyTrx.
+func PrepareImageObject(obj *quorumpb.Object, thumbnailSize int) error {
+	for _, img := range obj.Image {
+		if err := ValidateImage(img); err != nil {
+			return err
+		}
+		if err := StripImageMetadata(img); err != nil {
+			return err
+		}
+	}
+	if len(obj.Image) == 0 || obj.Preview != nil {
+		return nil
+	}
+	thumb, err := ImageThumbnail(obj.Image[0], thumbnailSize)
+	if err != nil {
+		return err
+	}
+	obj.Preview = &quorumpb.Object{Type: "Image", MediaType: thumb.MediaType, Image: []*quorumpb.Image{thumb}}
+	return nil
+}
+
+// box filter downscale, each destination pixel is the average of the source pixels it covers
+func downscaleImage(src image.Image, maxSize int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	tw, th := w, h
+	if w > maxSize || h > maxSize {
+		if w >= h {
+			tw, th = maxSize, h*maxSize/w
+		} else {
+			tw, th = w*maxSize/h, maxSize
+		}
+	}
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := bounds.Min.Y+y*h/th, bounds.Min.Y+(y+1)*h/th
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < tw; x++ {
+			x0, x1 := bounds.Min.X+x*w/tw, bounds.Min.X+(x+1)*w/tw
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBA64Model.Convert(src.At(sx, sy)).(color.NRGBA64)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					b += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.Set(x, y, color.NRGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+// removes the APP1 (EXIF, XMP), APP13 (IPTC) and COM segments before the image data
+func stripJPEGMetadata(content []byte) ([]byte, error) {
+	if len(content) < 2 || content[0] != 0xff || content[1] != 0xd8 {
+		return nil, fmt.Errorf("not a jpeg image")
+	}
+	out := []byte{0xff, 0xd8}
+	pos := 2
+	for pos < len(content) {
+		if content[pos] != 0xff {
+			return nil, fmt.Errorf("invalid jpeg marker at %d", pos)
+		}
+		//skip fill bytes
+		for pos+1 < len(content) && content[pos+1] == 0xff {
+			pos++
+		}
+		if pos+1 >= len(content) {
+			return nil, fmt.Errorf("truncated jpeg")
+		}
+		marker := content[pos+1]
+		if marker == 0xda || marker == 0xd9 {
+			//start of scan or end of image, the rest is image data
+			return append(out, content[pos:]...), nil
+		}
+		if marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7) {
+			out = append(out, content[pos:pos+2]...)
+			pos += 2
+			continue
+		}
+		if pos+4 > len(content) {
+			return nil, fmt.Errorf("truncated jpeg")
+		}
+		length := int(binary.BigEndian.Uint16(content[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(content) {
+			return nil, fmt.Errorf("invalid jpeg segment length at %d", pos)
+		}
+		if marker != 0xe1 && marker != 0xed && marker != 0xfe {
+			out = append(out, content[pos:end]...)
+		}
+		pos = end
+	}
+	return nil, fmt.Errorf("jpeg has no image data")
+}
+
+// removes the eXIf, text and time chunks
+func stripPNGMetadata(content []byte) ([]byte, error) {
+	if !bytes.HasPrefix(content, pngSignature) {
+		return nil, fmt.Errorf("not a png image")
+	}
+	out := append([]byte{}, pngSignature...)
+	pos := len(pngSignature)
+	for pos < len(content) {
+		if pos+8 > len(content) {
+			return nil, fmt.Errorf("truncated png")
+		}
+		length := int(binary.BigEndian.Uint32(content[pos : pos+4]))
+		chunktype := string(content[pos+4 : pos+8])
+		end := pos + 12 + length
+		if length < 0 || end > len(content) || end < pos {
+			return nil, fmt.Errorf("invalid png chunk length at %d", pos)
+		}
+		if !pngMetadataChunks[chunktype] {
+			out = append(out, content[pos:end]...)
+		}
+		pos = end
+		if chunktype == "IEND" {
+			return out, nil
+		}
+	}
+	return nil, fmt.Errorf("png has no IEND chunk")
+}
@@ -0,0 +1,109 @@
+package data
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+)
+
+var testGPSMetadata = []byte("Exif\x00\x00GPSLatitude=31.2304 GPSLongitude=121.4737")
+
+func getTestImage(width, height int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+func getTestJPEGWithExif(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, getTestImage(800, 600), nil); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	app1 := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(testGPSMetadata)+2))
+	app1 = append(app1, testGPSMetadata...)
+	return append(append(append([]byte{}, encoded[:2]...), app1...), encoded[2:]...)
+}
+
+func getTestPNGWithText(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, getTestImage(100, 300)); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	data := append([]byte("tEXt"), testGPSMetadata...)
+	chunk := make([]byte, 4)
+	binary.BigEndian.PutUint32(chunk, uint32(len(testGPSMetadata)))
+	chunk = append(chunk, data...)
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(data))
+	chunk = append(chunk, crc...)
+	//insert the chunk after the signature and IHDR
+	ihdrend := len(pngSignature) + 12 + 13
+	return append(append(append([]byte{}, encoded[:ihdrend]...), chunk...), encoded[ihdrend:]...)
+}
+
+func TestValidateImage(t *testing.T) {
+	content := getTestJPEGWithExif(t)
+	if err := ValidateImage(&quorumpb.Image{MediaType: "image/jpeg", Content: content}); err != nil {
+		t.Errorf("valid jpeg rejected: %s", err)
+	}
+	if err := ValidateImage(&quorumpb.Image{MediaType: "image/png", Content: content}); err == nil {
+		t.Errorf("jpeg declared as png accepted")
+	}
+	if err := ValidateImage(&quorumpb.Image{MediaType: "image/png", Content: []byte("<html></html>")}); err == nil {
+		t.Errorf("html declared as png accepted")
+	}
+}
+
+func TestPrepareImageObject(t *testing.T) {
+	for _, c := range []struct {
+		mediaType string
+		content   []byte
+		thumbW    int
+		thumbH    int
+	}{
+		{"image/jpeg", getTestJPEGWithExif(t), 128, 96},
+		{"image/png", getTestPNGWithText(t), 42, 128},
+	} {
+		obj := &quorumpb.Object{Type: "Note", Image: []*quorumpb.Image{{Name: "photo", MediaType: c.mediaType, Content: c.content}}}
+		if err := PrepareImageObject(obj, 128); err != nil {
+			t.Fatalf("prepare %s object err: %s", c.mediaType, err)
+		}
+
+		content := obj.Image[0].Content
+		if bytes.Contains(content, []byte("GPSLatitude")) {
+			t.Errorf("%s metadata not stripped", c.mediaType)
+		}
+		if _, _, err := image.Decode(bytes.NewReader(content)); err != nil {
+			t.Errorf("stripped %s can not be decoded: %s", c.mediaType, err)
+		}
+
+		if obj.Preview == nil || len(obj.Preview.Image) != 1 {
+			t.Fatalf("%s object has no preview", c.mediaType)
+		}
+		thumb := obj.Preview.Image[0]
+		if thumb.MediaType != c.mediaType {
+			t.Errorf("thumbnail of %s is %s", c.mediaType, thumb.MediaType)
+		}
+		config, _, err := image.DecodeConfig(bytes.NewReader(thumb.Content))
+		if err != nil {
+			t.Fatalf("decode %s thumbnail err: %s", c.mediaType, err)
+		}
+		if config.Width != c.thumbW || config.Height != c.thumbH {
+			t.Errorf("%s thumbnail is %dx%d, expect %dx%d", c.mediaType, config.Width, config.Height, c.thumbW, c.thumbH)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package data
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// MembershipState folds the PRODUCER, USER and ANNOUNCE trxs of a group into the current
+// producer, user and announce sets. Trxs must be applied in block order with the height of their block,
+// a change made at height H takes effect from height H+1.
+// The group owner is a producer from the genesis block unless removed by a PRODUCER trx.
+type MembershipState struct {
+	groupId     string
+	ownerPubkey string
+
+	mu        sync.RWMutex
+	height    int64
+	producers map[string][]*producerTerm
+	users     map[string]*quorumpb.UserItem
+	announces map[announceKey]*quorumpb.AnnounceItem
+}
+
+// heights [From, To) a pubkey is allowed to produce blocks, To is 0 while the term is open
+type producerTerm struct {
+	From int64
+	To   int64
+}
+
+var (
+	_ RecipientResolver   = (*MembershipState)(nil)
+	_ EncryptPubkeyLookup = (*MembershipState)(nil)
+)
+
+type announceKey struct {
+	Type       quorumpb.AnnounceType
+	SignPubkey string
+}
+
+func NewMembershipState(groupId string, ownerPubkey string) *MembershipState {
+	state := &MembershipState{
+		groupId:     groupId,
+		ownerPubkey: ownerPubkey,
+		producers:   make(map[string][]*producerTerm),
+		users:       make(map[string]*quorumpb.UserItem),
+		announces:   make(map[announceKey]*quorumpb.AnnounceItem),
+	}
+	state.producers[ownerPubkey] = []*producerTerm{{From: 0}}
+	return state
+}
+
+// Height returns the height of the last applied trx
+func (state *MembershipState) Height() int64 {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	return state.height
+}
+
+// ApplyTrx applies a trx with its decrypted data, trxs of other types are ignored
+func (state *MembershipState) ApplyTrx(height int64, trx *quorumpb.Trx, data []byte) error {
+	if trx.GroupId != state.groupId {
+		return fmt.Errorf("trx %s for group %s, expect group %s", trx.TrxId, trx.GroupId, state.groupId)
+	}
+	switch trx.Type {
+	case quorumpb.TrxType_PRODUCER:
+		item := &quorumpb.ProducerItem{}
+		if err := proto.Unmarshal(data, item); err != nil {
+			return err
+		}
+		return state.ApplyProducerItem(height, trx.SenderPubkey, item)
+	case quorumpb.TrxType_USER:
+		item := &quorumpb.UserItem{}
+		if err := proto.Unmarshal(data, item); err != nil {
+			return err
+		}
+		return state.ApplyUserItem(height, trx.SenderPubkey, item)
+	case quorumpb.TrxType_ANNOUNCE:
+		item := &quorumpb.AnnounceItem{}
+		if err := proto.Unmarshal(data, item); err != nil {
+			return err
+		}
+		return state.ApplyAnnounceItem(height, trx.SenderPubkey, item)
+	}
+	return nil
+}
+
+// ApplyProducerItem adds or removes a producer, only the group owner can send it
+func (state *MembershipState) ApplyProducerItem(height int64, sender string, item *quorumpb.ProducerItem) error {
+	if err := state.checkOwnerItem(sender, item.GroupId, item.GroupOwnerPubkey); err != nil {
+		return err
+	}
+	if item.ProducerPubkey == "" {
+		return fmt.Errorf("producer pubkey must not be empty")
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if err := state.advance(height); err != nil {
+		return err
+	}
+	terms := state.producers[item.ProducerPubkey]
+	var current *producerTerm
+	if len(terms) > 0 && terms[len(terms)-1].To == 0 {
+		current = terms[len(terms)-1]
+	}
+	switch item.Action {
+	case quorumpb.ActionType_ADD:
+		if current == nil {
+			state.producers[item.ProducerPubkey] = append(terms, &producerTerm{From: height + 1})
+		}
+	case quorumpb.ActionType_REMOVE:
+		if current != nil {
+			current.To = height + 1
+		}
+	default:
+		return fmt.Errorf("unknown producer item action %s", item.Action)
+	}
+	return nil
+}
+
+// ApplyUserItem adds or removes a user, only the group owner can send it.
+// Removing a user also drops its announces, so its encrypt pubkey is no longer a recipient
+func (state *MembershipState) ApplyUserItem(height int64, sender string, item *quorumpb.UserItem) error {
+	if err := state.checkOwnerItem(sender, item.GroupId, item.GroupOwnerPubkey); err != nil {
+		return err
+	}
+	if item.UserPubkey == "" {
+		return fmt.Errorf("user pubkey must not be empty")
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if err := state.advance(height); err != nil {
+		return err
+	}
+	switch item.Action {
+	case quorumpb.ActionType_ADD:
+		state.users[item.UserPubkey] = proto.Clone(item).(*quorumpb.UserItem)
+	case quorumpb.ActionType_REMOVE:
+		delete(state.users, item.UserPubkey)
+		for _, announceType := range []quorumpb.AnnounceType{quorumpb.AnnounceType_AS_USER_ENCRYPT, quorumpb.AnnounceType_AS_USER} {
+			delete(state.announces, announceKey{Type: announceType, SignPubkey: item.UserPubkey})
+		}
+	default:
+		return fmt.Errorf("unknown user item action %s", item.Action)
+	}
+	return nil
+}
+
+// ApplyAnnounceItem records an announcement sent by the announcer itself,
+// or the approval or rejection of an announcement, which only the group owner can send
+func (state *MembershipState) ApplyAnnounceItem(height int64, sender string, item *quorumpb.AnnounceItem) error {
+	if item.GroupId != state.groupId {
+		return fmt.Errorf("announce item for group %s, expect group %s", item.GroupId, state.groupId)
+	}
+	if item.SignPubkey == "" {
+		return fmt.Errorf("announce sign pubkey must not be empty")
+	}
+
+	isowner := sender == state.ownerPubkey && item.OwnerPubkey == state.ownerPubkey
+	switch item.Result {
+	case quorumpb.ApproveType_ANNOUNCED:
+		if sender != item.SignPubkey && !isowner {
+			return fmt.Errorf("announce of %s must be sent by itself", item.SignPubkey)
+		}
+	case quorumpb.ApproveType_APPROVED, quorumpb.ApproveType_REJECTED:
+		if !isowner {
+			return fmt.Errorf("only group owner can %s announce of %s", item.Result, item.SignPubkey)
+		}
+	default:
+		return fmt.Errorf("unknown announce result %s", item.Result)
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if err := state.advance(height); err != nil {
+		return err
+	}
+	key := announceKey{Type: item.Type, SignPubkey: item.SignPubkey}
+	switch item.Action {
+	case quorumpb.ActionType_ADD:
+		if item.Result == quorumpb.ApproveType_ANNOUNCED {
+			//a new announcement resets a previous approval
+			state.announces[key] = proto.Clone(item).(*quorumpb.AnnounceItem)
+			return nil
+		}
+		announced, ok := state.announces[key]
+		if !ok {
+			return fmt.Errorf("no announce of %s to %s", item.SignPubkey, item.Result)
+		}
+		updated := proto.Clone(announced).(*quorumpb.AnnounceItem)
+		updated.Result = item.Result
+		updated.OwnerPubkey = item.OwnerPubkey
+		updated.OwnerSignature = item.OwnerSignature
+		state.announces[key] = updated
+	case quorumpb.ActionType_REMOVE:
+		delete(state.announces, key)
+	default:
+		return fmt.Errorf("unknown announce item action %s", item.Action)
+	}
+	return nil
+}
+
+// IsProducer reports whether pubkey is allowed to produce the block at height
+func (state *MembershipState) IsProducer(pubkey string, height int64) bool {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	return state.isProducer(pubkey, height)
+}
+
+// Producers returns the pubkeys allowed to produce the block at height, sorted
+func (state *MembershipState) Producers(height int64) []string {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	var producers []string
+	for pubkey := range state.producers {
+		if state.isProducer(pubkey, height) {
+			producers = append(producers, pubkey)
+		}
+	}
+	sort.Strings(producers)
+	return producers
+}
+
+func (state *MembershipState) isProducer(pubkey string, height int64) bool {
+	for _, term := range state.producers[pubkey] {
+		if height >= term.From && (term.To == 0 || height < term.To) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUser reports whether pubkey is a current group user
+func (state *MembershipState) IsUser(pubkey string) bool {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	_, ok := state.users[pubkey]
+	return ok
+}
+
+// Users returns the current user items, sorted by user pubkey
+func (state *MembershipState) Users() []*quorumpb.UserItem {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	users := make([]*quorumpb.UserItem, 0, len(state.users))
+	for _, item := range state.users {
+		users = append(users, proto.Clone(item).(*quorumpb.UserItem))
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].UserPubkey < users[j].UserPubkey })
+	return users
+}
+
+// Announces returns the current announce items of the given type, sorted by sign pubkey
+func (state *MembershipState) Announces(announceType quorumpb.AnnounceType) []*quorumpb.AnnounceItem {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	var items []*quorumpb.AnnounceItem
+	for key, item := range state.announces {
+		if key.Type == announceType {
+			items = append(items, proto.Clone(item).(*quorumpb.AnnounceItem))
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].SignPubkey < items[j].SignPubkey })
+	return items
+}
+
+// EncryptPubkeys returns the encrypt pubkeys a private group POST should be encrypted to:
+// the keys of the approved AS_USER_ENCRYPT announces and of the current users, sorted and deduplicated
+func (state *MembershipState) EncryptPubkeys() []string {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	keys := make(map[string]bool)
+	for key, item := range state.announces {
+		if key.Type == quorumpb.AnnounceType_AS_USER_ENCRYPT && item.Result == quorumpb.ApproveType_APPROVED && item.EncryptPubkey != "" {
+			keys[item.EncryptPubkey] = true
+		}
+	}
+	for _, item := range state.users {
+		if item.EncryptPubkey != "" {
+			keys[item.EncryptPubkey] = true
+		}
+	}
+	pubkeys := make([]string, 0, len(keys))
+	for key := range keys {
+		pubkeys = append(pubkeys, key)
+	}
+	sort.Strings(pubkeys)
+	return pubkeys
+}
+
+// GetEncryptPubkeys implements RecipientResolver
+func (state *MembershipState) GetEncryptPubkeys(groupId string) ([]string, error) {
+	if groupId != state.groupId {
+		return nil, fmt.Errorf("no membership of group %s", groupId)
+	}
+	return state.EncryptPubkeys(), nil
+}
+
+// GetEncryptPubkey implements EncryptPubkeyLookup, with the key of the user item,
+// else of an approved announce, else of any announce of the sign pubkey
+func (state *MembershipState) GetEncryptPubkey(groupId string, signPubkey string) (string, error) {
+	if groupId != state.groupId {
+		return "", fmt.Errorf("no membership of group %s", groupId)
+	}
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	if item, ok := state.users[signPubkey]; ok && item.EncryptPubkey != "" {
+		return item.EncryptPubkey, nil
+	}
+	var pubkey string
+	for _, announceType := range []quorumpb.AnnounceType{quorumpb.AnnounceType_AS_USER_ENCRYPT, quorumpb.AnnounceType_AS_USER} {
+		item, ok := state.announces[announceKey{Type: announceType, SignPubkey: signPubkey}]
+		if !ok || item.EncryptPubkey == "" {
+			continue
+		}
+		if item.Result == quorumpb.ApproveType_APPROVED {
+			return item.EncryptPubkey, nil
+		}
+		if pubkey == "" {
+			pubkey = item.EncryptPubkey
+		}
+	}
+	if pubkey == "" {
+		return "", fmt.Errorf("no encrypt pubkey announced by %s", signPubkey)
+	}
+	return pubkey, nil
+}
+
+func (state *MembershipState) checkOwnerItem(sender string, groupId string, ownerPubkey string) error {
+	if groupId != state.groupId {
+		return fmt.Errorf("item for group %s, expect group %s", groupId, state.groupId)
+	}
+	if sender != state.ownerPubkey || ownerPubkey != state.ownerPubkey {
+		return fmt.Errorf("item must be sent by group owner %s", state.ownerPubkey)
+	}
+	return nil
+}
+
+// must be called with state.mu locked
+func (state *MembershipState) advance(height int64) error {
+	if height < state.height {
+		return fmt.Errorf("trx at height %d applied after height %d", height, state.height)
+	}
+	state.height = height
+	return nil
+}
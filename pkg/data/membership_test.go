@@ -0,0 +1,85 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+)
+
+func TestMembershipStateProducers(t *testing.T) {
+	groupId := GetGroupItem().GroupId
+	owner, producer := "owner_pubkey", "producer_pubkey"
+	state := NewMembershipState(groupId, owner)
+
+	add := &quorumpb.ProducerItem{GroupId: groupId, ProducerPubkey: producer, GroupOwnerPubkey: owner, Action: quorumpb.ActionType_ADD}
+	if err := state.ApplyProducerItem(10, producer, add); err == nil {
+		t.Errorf("producer item not sent by owner accepted")
+	}
+	if err := state.ApplyProducerItem(10, owner, add); err != nil {
+		t.Fatalf("apply producer item err: %s", err)
+	}
+	remove := &quorumpb.ProducerItem{GroupId: groupId, ProducerPubkey: producer, GroupOwnerPubkey: owner, Action: quorumpb.ActionType_REMOVE}
+	if err := state.ApplyProducerItem(20, owner, remove); err != nil {
+		t.Fatalf("apply producer item err: %s", err)
+	}
+	if err := state.ApplyProducerItem(15, owner, add); err == nil {
+		t.Errorf("item applied out of block order accepted")
+	}
+
+	for height, expect := range map[int64]bool{10: false, 11: true, 20: true, 21: false} {
+		if state.IsProducer(producer, height) != expect {
+			t.Errorf("IsProducer at height %d, expect %v", height, expect)
+		}
+	}
+	if !state.IsProducer(owner, 0) || !state.IsProducer(owner, 100) {
+		t.Errorf("owner should be a producer")
+	}
+	if producers := state.Producers(15); !reflect.DeepEqual(producers, []string{owner, producer}) {
+		t.Errorf("Producers at height 15 got %v", producers)
+	}
+}
+
+func TestMembershipStateEncryptPubkeys(t *testing.T) {
+	groupId := GetGroupItem().GroupId
+	owner := "owner_pubkey"
+	state := NewMembershipState(groupId, owner)
+
+	announce := func(signpubkey, encryptpubkey string) *quorumpb.AnnounceItem {
+		return &quorumpb.AnnounceItem{GroupId: groupId, SignPubkey: signpubkey, EncryptPubkey: encryptpubkey, Type: quorumpb.AnnounceType_AS_USER_ENCRYPT, Result: quorumpb.ApproveType_ANNOUNCED}
+	}
+	approve := func(signpubkey string, result quorumpb.ApproveType) *quorumpb.AnnounceItem {
+		return &quorumpb.AnnounceItem{GroupId: groupId, SignPubkey: signpubkey, Type: quorumpb.AnnounceType_AS_USER_ENCRYPT, OwnerPubkey: owner, Result: result}
+	}
+
+	steps := []struct {
+		sender string
+		item   *quorumpb.AnnounceItem
+	}{
+		{"user1", announce("user1", "age_user1")},
+		{"user2", announce("user2", "age_user2")},
+		{"user3", announce("user3", "age_user3")},
+		{owner, approve("user1", quorumpb.ApproveType_APPROVED)},
+		{owner, approve("user2", quorumpb.ApproveType_REJECTED)},
+	}
+	for i, step := range steps {
+		if err := state.ApplyAnnounceItem(int64(i+1), step.sender, step.item); err != nil {
+			t.Fatalf("apply announce item %d err: %s", i, err)
+		}
+	}
+	if err := state.ApplyAnnounceItem(10, "user3", approve("user3", quorumpb.ApproveType_APPROVED)); err == nil {
+		t.Errorf("approve not sent by owner accepted")
+	}
+	if err := state.ApplyAnnounceItem(10, "user4", announce("user3", "age_fake")); err == nil {
+		t.Errorf("announce sent by another user accepted")
+	}
+
+	user := &quorumpb.UserItem{GroupId: groupId, UserPubkey: "user5", EncryptPubkey: "age_user5", GroupOwnerPubkey: owner, Action: quorumpb.ActionType_ADD}
+	if err := state.ApplyUserItem(11, owner, user); err != nil {
+		t.Fatalf("apply user item err: %s", err)
+	}
+
+	if keys := state.EncryptPubkeys(); !reflect.DeepEqual(keys, []string{"age_user1", "age_user5"}) {
+		t.Errorf("EncryptPubkeys got %v", keys)
+	}
+}
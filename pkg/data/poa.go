@@ -0,0 +1,187 @@
+package data
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+)
+
+const (
+	POA_SLOT_DURATION = 5 * time.Second //default duration of a producer slot
+	POA_MAX_DRIFT     = 1 * time.Second //default clock drift allowed to block timestamps
+)
+
+// PoaSchedule rotates the producers of a POA group over time slots: slot S starts at
+// genesis TimeStamp + S * slot duration and belongs to producer S mod N of the N producers, sorted by
+// pubkey, allowed by the MembershipState at the height of the block. A block must be produced by the
+// owner of its slot, in a later slot than the previous block, the genesis block being in slot 0, and its
+// timestamp must not be ahead of the local clock by more than the max drift: a producer can not take the
+// slots of the others in advance. The slots without a block are counted as missed by their producer.
+type PoaSchedule struct {
+	membership *MembershipState
+	genesis    int64
+	slot       int64
+	maxDrift   int64
+	now        func() time.Time
+
+	mu       sync.RWMutex
+	lastSlot int64
+	stats    map[string]*ProducerStats
+}
+
+// ProducerStats counts the blocks produced and the slots missed by a producer
+type ProducerStats struct {
+	Pubkey        string
+	BlockProduced int64
+	SlotsMissed   int64
+}
+
+// PoaScheduleOptions overrides the slot duration, the max drift and the clock of a PoaSchedule
+type PoaScheduleOptions struct {
+	Slot     time.Duration    //POA_SLOT_DURATION if 0
+	MaxDrift time.Duration    //POA_MAX_DRIFT if 0
+	Now      func() time.Time //time.Now if nil
+}
+
+// NewPoaSchedule starts the schedule at the genesis block
+func NewPoaSchedule(membership *MembershipState, genesis *quorumpb.Block, opts *PoaScheduleOptions) *PoaSchedule {
+	schedule := &PoaSchedule{
+		membership: membership,
+		genesis:    genesis.TimeStamp,
+		slot:       int64(POA_SLOT_DURATION),
+		maxDrift:   int64(POA_MAX_DRIFT),
+		now:        time.Now,
+		stats:      make(map[string]*ProducerStats),
+	}
+	if opts != nil {
+		if opts.Slot > 0 {
+			schedule.slot = int64(opts.Slot)
+		}
+		if opts.MaxDrift > 0 {
+			schedule.maxDrift = int64(opts.MaxDrift)
+		}
+		if opts.Now != nil {
+			schedule.now = opts.Now
+		}
+	}
+	return schedule
+}
+
+// Slot returns the slot of a block timestamp
+func (schedule *PoaSchedule) Slot(timestamp int64) (int64, error) {
+	if timestamp < schedule.genesis {
+		return 0, fmt.Errorf("timestamp %d before the genesis block", timestamp)
+	}
+	return (timestamp - schedule.genesis) / schedule.slot, nil
+}
+
+// SlotProducer returns the producer of slot for the block at height
+func (schedule *PoaSchedule) SlotProducer(height int64, slot int64) (string, error) {
+	producers := schedule.membership.Producers(height)
+	if len(producers) == 0 {
+		return "", fmt.Errorf("no producer at height %d", height)
+	}
+	return producers[slot%int64(len(producers))], nil
+}
+
+// Producer returns the producer allowed to produce the block at height with timestamp
+func (schedule *PoaSchedule) Producer(height int64, timestamp int64) (string, error) {
+	slot, err := schedule.Slot(timestamp)
+	if err != nil {
+		return "", err
+	}
+	return schedule.SlotProducer(height, slot)
+}
+
+// Validator returns a BlockValidator for IsBlockValid rejecting the block at height if it is not produced
+// by the producer of its slot, in a slot which has started, after the slot of the previous block
+func (schedule *PoaSchedule) Validator(height int64) BlockValidator {
+	return func(newBlock, oldBlock *quorumpb.Block) error {
+		slot, err := schedule.startedSlot(newBlock)
+		if err != nil {
+			return err
+		}
+		prevslot, err := schedule.Slot(oldBlock.TimeStamp)
+		if err != nil {
+			return err
+		}
+		if slot <= prevslot {
+			return fmt.Errorf("%w: block in slot %d, after a block in slot %d", ErrIneligibleProducer, slot, prevslot)
+		}
+		producer, err := schedule.SlotProducer(height, slot)
+		if err != nil {
+			return err
+		}
+		if newBlock.ProducerPubKey != producer {
+			return fmt.Errorf("%w: %s in slot %d, expect %s", ErrIneligibleProducer, newBlock.ProducerPubKey, slot, producer)
+		}
+		return nil
+	}
+}
+
+// ApplyBlock updates the statistics with a validated block at height: the block is counted for its
+// producer, the slots since the previous block are counted as missed. Blocks must be applied in order.
+func (schedule *PoaSchedule) ApplyBlock(height int64, block *quorumpb.Block) error {
+	slot, err := schedule.startedSlot(block)
+	if err != nil {
+		return err
+	}
+	producers := schedule.membership.Producers(height)
+
+	schedule.mu.Lock()
+	defer schedule.mu.Unlock()
+	if slot <= schedule.lastSlot {
+		return fmt.Errorf("block %s in slot %d applied after slot %d", block.BlockId, slot, schedule.lastSlot)
+	}
+	//slot 0 is the slot of the genesis block
+	missed := slot - schedule.lastSlot - 1
+	if n := int64(len(producers)); n > 0 && missed > 0 {
+		for i, producer := range producers {
+			//slots of producer i in [lastSlot+1, slot)
+			first := (int64(i) - (schedule.lastSlot+1)%n + n) % n
+			if first < missed {
+				schedule.producerStats(producer).SlotsMissed += (missed-first-1)/n + 1
+			}
+		}
+	}
+	schedule.producerStats(block.ProducerPubKey).BlockProduced++
+	schedule.lastSlot = slot
+	return nil
+}
+
+// startedSlot returns the slot of a block which timestamp is not ahead of the clock by more than the max drift
+func (schedule *PoaSchedule) startedSlot(block *quorumpb.Block) (int64, error) {
+	slot, err := schedule.Slot(block.TimeStamp)
+	if err != nil {
+		return 0, err
+	}
+	if now := schedule.now().UnixNano(); block.TimeStamp > now+schedule.maxDrift {
+		return 0, fmt.Errorf("%w: block %s in slot %d, timestamp %d ahead of the clock %d", ErrIneligibleProducer, block.BlockId, slot, block.TimeStamp, now)
+	}
+	return slot, nil
+}
+
+// Stats returns the statistics of the producers, sorted by pubkey
+func (schedule *PoaSchedule) Stats() []ProducerStats {
+	schedule.mu.RLock()
+	defer schedule.mu.RUnlock()
+	stats := make([]ProducerStats, 0, len(schedule.stats))
+	for _, s := range schedule.stats {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Pubkey < stats[j].Pubkey })
+	return stats
+}
+
+// must be called with schedule.mu locked
+func (schedule *PoaSchedule) producerStats(pubkey string) *ProducerStats {
+	s, ok := schedule.stats[pubkey]
+	if !ok {
+		s = &ProducerStats{Pubkey: pubkey}
+		schedule.stats[pubkey] = s
+	}
+	return s
+}
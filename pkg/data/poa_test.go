@@ -0,0 +1,111 @@
+package data
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+)
+
+func TestPoaSchedule(t *testing.T) {
+	groupId := GetGroupItem().GroupId
+	owner, producer := "owner_pubkey", "producer_pubkey"
+	membership := NewMembershipState(groupId, owner)
+	add := &quorumpb.ProducerItem{GroupId: groupId, ProducerPubkey: producer, GroupOwnerPubkey: owner, Action: quorumpb.ActionType_ADD}
+	if err := membership.ApplyProducerItem(0, owner, add); err != nil {
+		t.Fatal(err)
+	}
+
+	genesis := &quorumpb.Block{BlockId: "genesis", TimeStamp: 1000}
+	schedule := NewPoaSchedule(membership, genesis, &PoaScheduleOptions{Slot: 10})
+	block := func(id string, slot int64, pubkey string) *quorumpb.Block {
+		return &quorumpb.Block{BlockId: id, PrevBlockId: "genesis", TimeStamp: genesis.TimeStamp + slot*10 + 5, ProducerPubKey: pubkey}
+	}
+
+	//the producers sorted by pubkey take the slots in turn
+	for slot, expect := range map[int64]string{0: owner, 1: producer, 2: owner, 7: producer} {
+		if got, err := schedule.Producer(1, genesis.TimeStamp+slot*10); err != nil || got != expect {
+			t.Errorf("producer of slot %d is %s, %v, expect %s", slot, got, err, expect)
+		}
+	}
+	if err := schedule.Validator(1)(block("b1", 1, producer), genesis); err != nil {
+		t.Errorf("block of the slot producer rejected: %s", err)
+	}
+	if err := schedule.Validator(1)(block("b1", 1, owner), genesis); !errors.Is(err, ErrIneligibleProducer) {
+		t.Errorf("block of another producer: %v", err)
+	}
+	if err := schedule.Validator(2)(block("b2", 1, producer), block("b1", 1, producer)); !errors.Is(err, ErrIneligibleProducer) {
+		t.Errorf("second block in a slot: %v", err)
+	}
+	//the added producer is not scheduled before it is allowed
+	if got, _ := schedule.Producer(0, genesis.TimeStamp+10); got != owner {
+		t.Errorf("producer of slot 1 at height 0 is %s, expect the owner", got)
+	}
+
+	chain := []*quorumpb.Block{block("b1", 1, producer), block("b2", 2, owner), block("b3", 5, producer), block("b4", 100, owner)}
+	for i, b := range chain {
+		if err := schedule.ApplyBlock(int64(i+1), b); err != nil {
+			t.Fatalf("apply block %s err: %s", b.BlockId, err)
+		}
+	}
+	if err := schedule.ApplyBlock(5, block("b5", 100, owner)); err == nil {
+		t.Errorf("block applied in a past slot")
+	}
+	//slots 3, 4 and 6 to 99 are missed
+	expect := []ProducerStats{{Pubkey: owner, BlockProduced: 2, SlotsMissed: 48}, {Pubkey: producer, BlockProduced: 2, SlotsMissed: 48}}
+	if stats := schedule.Stats(); !reflect.DeepEqual(stats, expect) {
+		t.Errorf("stats %v, expect %v", stats, expect)
+	}
+}
+
+func TestPoaBlockValidation(t *testing.T) {
+	seed, blocks := getTestChain(t, 3)
+	membership := NewMembershipState(seed.GroupId, seed.OwnerPubkey)
+	schedule := NewPoaSchedule(membership, seed.GenesisBlock, &PoaScheduleOptions{Slot: 1})
+	prev := seed.GenesisBlock
+	for i, block := range blocks {
+		if ok, err := IsBlockValid(block, prev, schedule.Validator(int64(i+1))); !ok {
+			t.Errorf("block of the only producer rejected: %s", err)
+		}
+		prev = block
+	}
+
+	//a key which is not a producer of the group has no slot
+	schedule = NewPoaSchedule(NewMembershipState(seed.GroupId, "other_pubkey"), seed.GenesisBlock, &PoaScheduleOptions{Slot: 1})
+	if ok, err := IsBlockValid(blocks[0], seed.GenesisBlock, schedule.Validator(1)); ok || !errors.Is(err, ErrIneligibleProducer) {
+		t.Errorf("block of a producer not scheduled: %v, %v", ok, err)
+	}
+}
+
+func TestPoaScheduleFutureSlot(t *testing.T) {
+	groupId := GetGroupItem().GroupId
+	owner := "owner_pubkey"
+	membership := NewMembershipState(groupId, owner)
+	genesis := &quorumpb.Block{BlockId: "genesis", TimeStamp: 1000}
+	now := time.Unix(0, genesis.TimeStamp+10*100)
+	schedule := NewPoaSchedule(membership, genesis, &PoaScheduleOptions{Slot: 100, MaxDrift: 10, Now: func() time.Time { return now }})
+
+	//the slot 10 has started, the slot 11 starts after the max drift
+	current := &quorumpb.Block{BlockId: "b1", TimeStamp: now.UnixNano() + 10, ProducerPubKey: owner}
+	future := &quorumpb.Block{BlockId: "b1", TimeStamp: genesis.TimeStamp + 11*100, ProducerPubKey: owner}
+	if err := schedule.Validator(1)(current, genesis); err != nil {
+		t.Errorf("block within the max drift rejected: %s", err)
+	}
+	if err := schedule.Validator(1)(future, genesis); !errors.Is(err, ErrIneligibleProducer) {
+		t.Errorf("block of a slot not started: %v", err)
+	}
+	if err := schedule.ApplyBlock(1, future); err == nil {
+		t.Errorf("block of a slot not started applied")
+	}
+	if stats := schedule.Stats(); len(stats) != 0 {
+		t.Errorf("block of a slot not started counted: %v", stats)
+	}
+	if err := schedule.ApplyBlock(1, current); err != nil {
+		t.Fatal(err)
+	}
+	if stats := schedule.Stats(); len(stats) != 1 || stats[0].BlockProduced != 1 || stats[0].SlotsMissed != 9 {
+		t.Errorf("unexpected stats %v", stats)
+	}
+}
@@ -0,0 +1,188 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// PosState folds the STAKE trxs of a POS group into the stake of every producer and selects the leader
+// allowed to produce each block. Trxs must be applied in block order with the height of their block,
+// a stake set at height H counts from height H+1. Only the group owner can set stakes, the owner is the
+// leader while no producer has stake.
+//
+// The leader of a height is drawn from the hash of the previous block, so the schedule is only known
+// one block ahead. The producer of the previous block can grind its hash, so the hash only chooses among
+// the other producers: whether the previous producer leads again is drawn from the genesis hash and the
+// height, which it can not change. Each producer still leads with the probability of its stake, and a
+// producer grinding its blocks only chooses which other producer leads next, not its own share.
+// Producers colluding to choose each other are not covered.
+type PosState struct {
+	groupId     string
+	ownerPubkey string
+	genesisHash []byte
+
+	mu     sync.RWMutex
+	height int64
+	stakes map[string][]*stakeChange
+}
+
+// the stake of a producer from height From, until the next change
+type stakeChange struct {
+	From  int64
+	Stake int64
+}
+
+// ProducerStake is the stake of a producer at a height
+type ProducerStake struct {
+	Pubkey string
+	Stake  int64
+}
+
+func NewPosState(groupId string, ownerPubkey string, genesis *quorumpb.Block) *PosState {
+	return &PosState{groupId: groupId, ownerPubkey: ownerPubkey, genesisHash: genesis.Hash, stakes: make(map[string][]*stakeChange)}
+}
+
+// Height returns the height of the last applied trx
+func (state *PosState) Height() int64 {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	return state.height
+}
+
+// ApplyTrx applies a trx with its decrypted data, trxs of other types are ignored
+func (state *PosState) ApplyTrx(height int64, trx *quorumpb.Trx, data []byte) error {
+	if trx.GroupId != state.groupId {
+		return fmt.Errorf("trx %s for group %s, expect group %s", trx.TrxId, trx.GroupId, state.groupId)
+	}
+	if trx.Type != quorumpb.TrxType_STAKE {
+		return nil
+	}
+	item := &quorumpb.StakeItem{}
+	if err := proto.Unmarshal(data, item); err != nil {
+		return err
+	}
+	return state.ApplyStakeItem(height, trx.SenderPubkey, item)
+}
+
+// ApplyStakeItem sets the stake of a producer from height+1, only the group owner can send it
+func (state *PosState) ApplyStakeItem(height int64, sender string, item *quorumpb.StakeItem) error {
+	if item.GroupId != state.groupId {
+		return fmt.Errorf("stake item for group %s, expect group %s", item.GroupId, state.groupId)
+	}
+	if sender != state.ownerPubkey || item.GroupOwnerPubkey != state.ownerPubkey {
+		return fmt.Errorf("stake item must be sent by group owner %s", state.ownerPubkey)
+	}
+	if item.ProducerPubkey == "" {
+		return fmt.Errorf("producer pubkey must not be empty")
+	}
+	if item.Stake < 0 {
+		return fmt.Errorf("invalid stake %d of producer %s", item.Stake, item.ProducerPubkey)
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if height < state.height {
+		return fmt.Errorf("trx at height %d applied after height %d", height, state.height)
+	}
+	state.height = height
+	changes := state.stakes[item.ProducerPubkey]
+	if len(changes) > 0 && changes[len(changes)-1].From == height+1 {
+		changes[len(changes)-1].Stake = item.Stake
+	} else {
+		state.stakes[item.ProducerPubkey] = append(changes, &stakeChange{From: height + 1, Stake: item.Stake})
+	}
+	return nil
+}
+
+// Stake returns the stake of pubkey at height
+func (state *PosState) Stake(pubkey string, height int64) int64 {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	return stakeAt(state.stakes[pubkey], height)
+}
+
+// Stakes returns the producers with stake at height, sorted by pubkey
+func (state *PosState) Stakes(height int64) []ProducerStake {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	var stakes []ProducerStake
+	for pubkey, changes := range state.stakes {
+		if stake := stakeAt(changes, height); stake > 0 {
+			stakes = append(stakes, ProducerStake{Pubkey: pubkey, Stake: stake})
+		}
+	}
+	sort.Slice(stakes, func(i, j int) bool { return stakes[i].Pubkey < stakes[j].Pubkey })
+	return stakes
+}
+
+// Leader returns the producer of the block at height after prev, every node selects the same leader.
+// The leader is drawn from the producers with stake at height, weighted by stake: the producer of prev
+// leads again if the draw on sha256(genesis hash | height) selects it, else the leader is drawn among
+// the other producers on sha256(prev hash | height).
+func (state *PosState) Leader(height int64, prev *quorumpb.Block) string {
+	stakes := state.Stakes(height)
+	if len(stakes) == 0 {
+		return state.ownerPubkey
+	}
+	if candidate := drawStake(stakes, state.genesisHash, height); candidate == prev.ProducerPubKey {
+		return candidate
+	}
+	others := make([]ProducerStake, 0, len(stakes))
+	for _, stake := range stakes {
+		if stake.Pubkey != prev.ProducerPubKey {
+			others = append(others, stake)
+		}
+	}
+	return drawStake(others, prev.Hash, height)
+}
+
+// Validator returns a BlockValidator for IsBlockValid rejecting the block at height if its producer
+// is not the leader after the old block
+func (state *PosState) Validator(height int64) BlockValidator {
+	return func(newBlock, oldBlock *quorumpb.Block) error {
+		if leader := state.Leader(height, oldBlock); newBlock.ProducerPubKey != leader {
+			return fmt.Errorf("%w: %s at height %d, expect leader %s", ErrIneligibleProducer, newBlock.ProducerPubKey, height, leader)
+		}
+		return nil
+	}
+}
+
+// drawStake draws a pubkey of stakes weighted by stake, with sha256(seed | height) as the random source
+func drawStake(stakes []ProducerStake, seed []byte, height int64) string {
+	total := new(big.Int)
+	for _, stake := range stakes {
+		total.Add(total, big.NewInt(stake.Stake))
+	}
+
+	hash := sha256.New()
+	hash.Write(seed)
+	binary.Write(hash, binary.BigEndian, height)
+	draw := new(big.Int).Mod(new(big.Int).SetBytes(hash.Sum(nil)), total)
+
+	cumulated := new(big.Int)
+	for _, stake := range stakes {
+		cumulated.Add(cumulated, big.NewInt(stake.Stake))
+		if draw.Cmp(cumulated) < 0 {
+			return stake.Pubkey
+		}
+	}
+	return stakes[len(stakes)-1].Pubkey
+}
+
+func stakeAt(changes []*stakeChange, height int64) int64 {
+	var stake int64
+	for _, change := range changes {
+		if change.From > height {
+			break
+		}
+		stake = change.Stake
+	}
+	return stake
+}
@@ -0,0 +1,175 @@
+package data
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+)
+
+func TestPosStateStakes(t *testing.T) {
+	groupId := GetGroupItem().GroupId
+	owner, producer := "owner_pubkey", "producer_pubkey"
+	state := NewPosState(groupId, owner, &quorumpb.Block{Hash: []byte("genesis")})
+
+	stake := func(height int64, sender string, amount int64) error {
+		return state.ApplyStakeItem(height, sender, &quorumpb.StakeItem{GroupId: groupId, ProducerPubkey: producer, Stake: amount, GroupOwnerPubkey: owner})
+	}
+	if err := stake(10, producer, 5); err == nil {
+		t.Errorf("stake item not sent by owner accepted")
+	}
+	if err := stake(10, owner, -1); err == nil {
+		t.Errorf("negative stake accepted")
+	}
+	if err := stake(10, owner, 5); err != nil {
+		t.Fatalf("apply stake item err: %s", err)
+	}
+	if err := stake(20, owner, 0); err != nil {
+		t.Fatalf("apply stake item err: %s", err)
+	}
+	if err := stake(15, owner, 1); err == nil {
+		t.Errorf("item applied out of block order accepted")
+	}
+
+	for height, expect := range map[int64]int64{10: 0, 11: 5, 20: 5, 21: 0} {
+		if got := state.Stake(producer, height); got != expect {
+			t.Errorf("stake at height %d is %d, expect %d", height, got, expect)
+		}
+	}
+	if stakes := state.Stakes(21); len(stakes) != 0 {
+		t.Errorf("producer without stake listed: %v", stakes)
+	}
+	if leader := state.Leader(21, &quorumpb.Block{Hash: []byte("prev")}); leader != owner {
+		t.Errorf("leader without stakes is %s, expect the owner", leader)
+	}
+	if leader := state.Leader(11, &quorumpb.Block{Hash: []byte("prev"), ProducerPubKey: producer}); leader != producer {
+		t.Errorf("leader is %s, expect the only staked producer", leader)
+	}
+}
+
+// simulates a chain where every block is produced by the selected leader. A grinder which leads a block
+// tries grind variants of it, with other hashes, and keeps the first one after which it leads again.
+func simulatePosChain(t *testing.T, state *PosState, from, to int64, grinder string, grind int) (map[string]int, []*quorumpb.Block) {
+	counts := make(map[string]int)
+	prev := &quorumpb.Block{Hash: []byte("genesis")}
+	chain := []*quorumpb.Block{prev}
+	for height := from; height < to; height++ {
+		leader := state.Leader(height, prev)
+		counts[leader]++
+		variants := 1
+		if leader == grinder {
+			variants = grind
+		}
+		var block *quorumpb.Block
+		for i := 0; i < variants; i++ {
+			hash := sha256.Sum256([]byte(fmt.Sprintf("%x %s %d", prev.Hash, leader, i)))
+			block = &quorumpb.Block{Hash: hash[:], ProducerPubKey: leader}
+			next := &quorumpb.Block{ProducerPubKey: grinder, PreviousHash: block.Hash}
+			if state.Validator(height+1)(next, block) == nil {
+				break
+			}
+		}
+		if err := state.Validator(height)(block, prev); err != nil {
+			t.Fatalf("block of the leader rejected at height %d: %s", height, err)
+		}
+		prev = block
+		chain = append(chain, block)
+	}
+	return counts, chain
+}
+
+func TestPosLeaderSimulation(t *testing.T) {
+	groupId := GetGroupItem().GroupId
+	owner := "owner_pubkey"
+	newstate := func() *PosState {
+		state := NewPosState(groupId, owner, &quorumpb.Block{Hash: []byte("genesis")})
+		for i, amount := range []int64{10, 20, 30, 40, 0} {
+			item := &quorumpb.StakeItem{GroupId: groupId, ProducerPubkey: fmt.Sprintf("producer%d", i), Stake: amount, GroupOwnerPubkey: owner}
+			if err := state.ApplyStakeItem(0, owner, item); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return state
+	}
+
+	const blocks = 20000
+	state := newstate()
+	counts, chain := simulatePosChain(t, state, 1, blocks+1, "", 0)
+	for i, amount := range []int64{10, 20, 30, 40, 0} {
+		share := float64(counts[fmt.Sprintf("producer%d", i)]) / blocks
+		if expect := float64(amount) / 100; math.Abs(share-expect) > 0.02 {
+			t.Errorf("producer%d with stake %d led %.3f of the blocks, expect %.2f", i, amount, share, expect)
+		}
+	}
+	if counts[owner] != 0 {
+		t.Errorf("owner without stake led %d blocks", counts[owner])
+	}
+
+	//another node with the same chain selects the same leaders
+	other := newstate()
+	for height := int64(1); height <= 1000; height++ {
+		if leader, got := chain[height].ProducerPubKey, other.Leader(height, chain[height-1]); got != leader {
+			t.Fatalf("nodes disagree on the leader at height %d: %s and %s", height, leader, got)
+		}
+	}
+
+	//the leader depends on the previous block, the schedule is not known in advance
+	leaders := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		prev := &quorumpb.Block{Hash: []byte(fmt.Sprintf("prev%d", i)), ProducerPubKey: "producer3"}
+		leaders[state.Leader(blocks+1, prev)] = true
+	}
+	if len(leaders) < 3 {
+		t.Errorf("leaders after 100 previous blocks: %v", leaders)
+	}
+
+	//a producer grinding its blocks does not lead more than its stake
+	counts, _ = simulatePosChain(t, newstate(), 1, blocks+1, "producer0", 100)
+	if share := float64(counts["producer0"]) / blocks; math.Abs(share-0.1) > 0.02 {
+		t.Errorf("grinding producer0 with stake 10 led %.3f of the blocks, expect 0.10", share)
+	}
+
+	//unstaking a producer removes it from the schedule of the next blocks
+	unstake := &quorumpb.StakeItem{GroupId: groupId, ProducerPubkey: "producer3", Stake: 0, GroupOwnerPubkey: owner}
+	if err := state.ApplyStakeItem(blocks, owner, unstake); err != nil {
+		t.Fatal(err)
+	}
+	counts, _ = simulatePosChain(t, state, blocks+1, blocks+2001, "", 0)
+	if counts["producer3"] != 0 || counts["producer2"] == 0 {
+		t.Errorf("unexpected leaders after unstake: %v", counts)
+	}
+}
+
+func TestPosBlockValidation(t *testing.T) {
+	seed, blocks := getTestChain(t, 1)
+	groupId, owner := seed.GroupId, seed.OwnerPubkey
+
+	state := NewPosState(groupId, owner, seed.GenesisBlock)
+	if ok, err := IsBlockValid(blocks[0], seed.GenesisBlock, state.Validator(1)); !ok {
+		t.Errorf("block of the owner rejected without stakes: %s", err)
+	}
+
+	trxFactory := &TrxFactory{}
+	groupitem := GetGroupItem()
+	groupitem.UserSignPubkey = owner
+	trxFactory.Init("1.0.0", groupitem, "default", &TestNonce{})
+	trx, err := trxFactory.GetUpdStakeTrx("", &quorumpb.StakeItem{GroupId: groupId, ProducerPubkey: "producer_pubkey", Stake: 1, GroupOwnerPubkey: owner})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := NewCipherKeyRing(groupitem).DecryptTrx(trx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := state.ApplyTrx(0, trx, data); err != nil {
+		t.Fatalf("apply stake trx err: %s", err)
+	}
+
+	ok, err := IsBlockValid(blocks[0], seed.GenesisBlock, state.Validator(1))
+	if ok || !errors.Is(err, ErrIneligibleProducer) || IsInvalidData(err) {
+		t.Errorf("block of a producer not eligible: %v, %v", ok, err)
+	}
+}
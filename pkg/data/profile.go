@@ -0,0 +1,184 @@
+package data
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/ethereum/go-ethereum/common"
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+const PROFILE_NAME_LENGTH_LIMIT = 64 //characters
+
+// PaymentValidators checks Payment.Id by Payment.Type (upper case), a Payment of another type is rejected
+var PaymentValidators = map[string]func(id string) error{
+	"ETH":   validateEthAddress,
+	"MIXIN": validateMixinId,
+}
+
+var mixinIdPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// Profile is the latest profile published by a sender
+type Profile struct {
+	Pubkey    string
+	Person    *quorumpb.Person
+	TrxId     string //last trx which updated the profile
+	TimeStamp int64
+}
+
+// ValidatePayment checks the id of a Payment matches its type
+func ValidatePayment(payment *quorumpb.Payment) error {
+	validate, ok := PaymentValidators[strings.ToUpper(payment.Type)]
+	if !ok {
+		return fmt.Errorf("unsupported payment type %q", payment.Type)
+	}
+	if err := validate(payment.Id); err != nil {
+		return fmt.Errorf("invalid %s payment: %s", payment.Type, err)
+	}
+	return nil
+}
+
+// ValidatePerson checks a profile update: a name, an avatar image or a wallet must be set,
+// the avatar must be a valid image and every payment a valid one
+func ValidatePerson(person *quorumpb.Person) error {
+	if person.Name == "" && person.Image == nil && len(person.Wallet) == 0 {
+		return fmt.Errorf("profile must have a name, an image or a wallet")
+	}
+	if utf8.RuneCountInString(person.Name) > PROFILE_NAME_LENGTH_LIMIT {
+		return fmt.Errorf("profile name over %d characters", PROFILE_NAME_LENGTH_LIMIT)
+	}
+	if person.Image != nil {
+		if err := ValidateImage(person.Image); err != nil {
+			return err
+		}
+	}
+	for _, payment := range person.Wallet {
+		if err := ValidatePayment(payment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the address must carry its EIP-55 checksum
+func validateEthAddress(id string) error {
+	if !strings.HasPrefix(id, "0x") || !common.IsHexAddress(id) {
+		return fmt.Errorf("%s is not a 0x address", id)
+	}
+	if checksummed := common.HexToAddress(id).Hex(); checksummed != id {
+		return fmt.Errorf("address %s has a bad checksum, expect %s", id, checksummed)
+	}
+	return nil
+}
+
+func validateMixinId(id string) error {
+	if !mixinIdPattern.MatchString(id) {
+		return fmt.Errorf("%s is not a mixin user id", id)
+	}
+	return nil
+}
+
+// ProfileActivity returns the content of a profile update trx
+func ProfileActivity(person *quorumpb.Person) *quorumpb.Activity {
+	return &quorumpb.Activity{Type: "Update", Person: person}
+}
+
+// isProfileUpdate tells whether content is an Update of the sender's Person
+func isProfileUpdate(activity *quorumpb.Activity) bool {
+	return activity.Type == "Update" && activity.Person != nil && activity.Object == nil
+}
+
+// ProfileState reduces the profile updates of a group, applied in block order, to the latest profile
+// of every sender. An update only replaces the fields it sets, so a name change keeps the avatar.
+type ProfileState struct {
+	groupId string
+
+	mu       sync.RWMutex
+	profiles map[string]*Profile
+}
+
+func NewProfileState(groupId string) *ProfileState {
+	return &ProfileState{groupId: groupId, profiles: make(map[string]*Profile)}
+}
+
+// ApplyTrxData decodes the (decrypted) data of a POST trx with DecodePostContent and applies it
+func (state *ProfileState) ApplyTrxData(trx *quorumpb.Trx, data []byte) error {
+	content, _, err := DecodePostContent(trx.TrxId, data)
+	if err != nil {
+		return err
+	}
+	return state.Apply(trx, content)
+}
+
+// Apply applies the decoded content of a POST trx, content other than a profile update is ignored
+func (state *ProfileState) Apply(trx *quorumpb.Trx, content proto.Message) error {
+	if trx.GroupId != state.groupId {
+		return fmt.Errorf("trx %s for group %s, expect group %s", trx.TrxId, trx.GroupId, state.groupId)
+	}
+	if trx.Type != quorumpb.TrxType_POST {
+		return fmt.Errorf("trx %s is %s, not %s", trx.TrxId, trx.Type, quorumpb.TrxType_POST)
+	}
+	activity, ok := content.(*quorumpb.Activity)
+	if !ok || !isProfileUpdate(activity) {
+		return nil
+	}
+	if err := ValidatePerson(activity.Person); err != nil {
+		return fmt.Errorf("profile update of trx %s: %s", trx.TrxId, err)
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	person := proto.Clone(activity.Person).(*quorumpb.Person)
+	if current, ok := state.profiles[trx.SenderPubkey]; ok {
+		if person.Name == "" {
+			person.Name = current.Person.Name
+		}
+		if person.Image == nil {
+			person.Image = current.Person.Image
+		}
+		if len(person.Wallet) == 0 {
+			person.Wallet = current.Person.Wallet
+		}
+	}
+	state.profiles[trx.SenderPubkey] = &Profile{
+		Pubkey:    trx.SenderPubkey,
+		Person:    person,
+		TrxId:     trx.TrxId,
+		TimeStamp: trx.TimeStamp,
+	}
+	return nil
+}
+
+// Get returns a copy of the latest profile of a sender
+func (state *ProfileState) Get(pubkey string) (*Profile, bool) {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	profile, ok := state.profiles[pubkey]
+	if !ok {
+		return nil, false
+	}
+	return profile.copy(), true
+}
+
+// Profiles returns a copy of all the profiles, sorted by sender pubkey
+func (state *ProfileState) Profiles() []*Profile {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	profiles := make([]*Profile, 0, len(state.profiles))
+	for _, profile := range state.profiles {
+		profiles = append(profiles, profile.copy())
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Pubkey < profiles[j].Pubkey })
+	return profiles
+}
+
+func (profile *Profile) copy() *Profile {
+	c := *profile
+	c.Person = proto.Clone(profile.Person).(*quorumpb.Person)
+	return &c
+}
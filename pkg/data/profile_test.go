@@ -0,0 +1,85 @@
+package data
+
+import (
+	"testing"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+)
+
+func TestValidatePayment(t *testing.T) {
+	for _, c := range []struct {
+		payment *quorumpb.Payment
+		valid   bool
+	}{
+		{&quorumpb.Payment{Type: "ETH", Id: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"}, true},
+		{&quorumpb.Payment{Type: "eth", Id: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"}, true},
+		{&quorumpb.Payment{Type: "ETH", Id: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"}, false},
+		{&quorumpb.Payment{Type: "ETH", Id: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD"}, false},
+		{&quorumpb.Payment{Type: "ETH", Id: "5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"}, false},
+		{&quorumpb.Payment{Type: "mixin", Id: "c0a1e2b6-1b3a-4c6e-9a3c-8d2f5e7a9b10"}, true},
+		{&quorumpb.Payment{Type: "mixin", Id: "not a uuid"}, false},
+		{&quorumpb.Payment{Type: "BTC", Id: "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"}, false},
+	} {
+		err := ValidatePayment(c.payment)
+		if c.valid && err != nil {
+			t.Errorf("valid payment %v rejected: %s", c.payment, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("invalid payment %v accepted", c.payment)
+		}
+	}
+}
+
+func TestProfileState(t *testing.T) {
+	keystoreDir := t.TempDir()
+	tn := &TestNonce{}
+	trxFactory := &TrxFactory{}
+	groupitem := GetGroupItem()
+	trxFactory.Init("1.0.0", groupitem, "default", tn)
+	_, pubkey, err := GetKeyStorePubKey(groupitem.GroupId, keystoreDir)
+	if err != nil {
+		t.Fatalf("keystore new key err : %s", err)
+	}
+	groupitem.UserSignPubkey = pubkey
+	trxFactory.SetActivityValidator(NewActivityValidator(nil))
+
+	wallet := []*quorumpb.Payment{{Type: "ETH", Id: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", Name: "eth"}}
+	if _, err := trxFactory.GetProfileUpdateTrx("", &quorumpb.Person{Name: "alice", Wallet: []*quorumpb.Payment{{Type: "ETH", Id: "0x0"}}}); err == nil {
+		t.Errorf("profile with an invalid wallet accepted")
+	}
+	trx, err := trxFactory.GetProfileUpdateTrx("", &quorumpb.Person{Name: "alice", Wallet: wallet})
+	if err != nil {
+		t.Fatalf("get profile update trx err: %s", err)
+	}
+
+	state := NewProfileState(groupitem.GroupId)
+	data, err := NewCipherKeyRing(groupitem).DecryptTrx(trx)
+	if err != nil {
+		t.Fatalf("decrypt profile update trx err: %s", err)
+	}
+	if err := state.ApplyTrxData(trx, data); err != nil {
+		t.Fatalf("apply profile update err: %s", err)
+	}
+
+	//a later update only replaces the fields it sets
+	update := &quorumpb.Trx{TrxId: "update", GroupId: groupitem.GroupId, Type: quorumpb.TrxType_POST, SenderPubkey: pubkey, TimeStamp: trx.TimeStamp + 1}
+	if err := state.Apply(update, ProfileActivity(&quorumpb.Person{Name: "alice2"})); err != nil {
+		t.Fatalf("apply profile update err: %s", err)
+	}
+	//posts are ignored
+	post := &quorumpb.Trx{TrxId: "post", GroupId: groupitem.GroupId, Type: quorumpb.TrxType_POST, SenderPubkey: "bob"}
+	if err := state.Apply(post, &quorumpb.Object{Type: "Note", Content: "hi"}); err != nil {
+		t.Fatalf("apply post err: %s", err)
+	}
+
+	profile, ok := state.Get(pubkey)
+	if !ok {
+		t.Fatalf("profile of %s not found", pubkey)
+	}
+	if profile.Person.Name != "alice2" || len(profile.Person.Wallet) != 1 || profile.TrxId != "update" {
+		t.Errorf("unexpected profile: %+v", profile)
+	}
+	if profiles := state.Profiles(); len(profiles) != 1 {
+		t.Errorf("expect 1 profile, got %d", len(profiles))
+	}
+}
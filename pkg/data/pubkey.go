@@ -0,0 +1,185 @@
+package data
+
+import (
+	"crypto/ecdsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
+	localcrypto "github.com/rumsystem/keystore/pkg/crypto"
+)
+
+// PubkeyKind is the format of a sender or producer pubkey
+type PubkeyKind int
+
+const (
+	PUBKEY_UNKNOWN     PubkeyKind = iota
+	PUBKEY_ETH                    //base64 url encoded compressed secp256k1 key
+	PUBKEY_ETH_ADDRESS            //0x address, the key is recovered from the signature
+	PUBKEY_LIBP2P                 //libp2p config encoded key, for backward compatibility
+)
+
+func (kind PubkeyKind) String() string {
+	switch kind {
+	case PUBKEY_ETH:
+		return "eth"
+	case PUBKEY_ETH_ADDRESS:
+		return "eth_address"
+	case PUBKEY_LIBP2P:
+		return "libp2p"
+	}
+	return "unknown"
+}
+
+// Pubkey is a parsed pubkey, only the field of its kind is set. It is immutable, so a Pubkey
+// resolved by a PubkeyResolver can be shared.
+type Pubkey struct {
+	kind    PubkeyKind
+	eth     *ecdsa.PublicKey
+	address common.Address
+	libp2p  p2pcrypto.PubKey
+}
+
+// ParsePubkey detects the format of a pubkey and parses it
+func ParsePubkey(s string) (*Pubkey, PubkeyKind, error) {
+	if len(s) == 42 && s[:2] == "0x" {
+		if !common.IsHexAddress(s) {
+			return nil, PUBKEY_UNKNOWN, fmt.Errorf("%w: invalid 0x address %s", ErrUnknownKeyFormat, s)
+		}
+		return &Pubkey{kind: PUBKEY_ETH_ADDRESS, address: common.HexToAddress(s)}, PUBKEY_ETH_ADDRESS, nil
+	}
+
+	bytespubkey, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil { //try eth key
+		if ethpubkey, err := ethcrypto.DecompressPubkey(bytespubkey); err == nil {
+			return &Pubkey{kind: PUBKEY_ETH, eth: ethpubkey}, PUBKEY_ETH, nil
+		}
+	}
+
+	//libp2p key for backward campatibility
+	serializedpub, err := p2pcrypto.ConfigDecodeKey(s)
+	if err != nil {
+		return nil, PUBKEY_UNKNOWN, fmt.Errorf("%w: %s", ErrUnknownKeyFormat, err)
+	}
+	p2ppubkey, err := p2pcrypto.UnmarshalPublicKey(serializedpub)
+	if err != nil {
+		p2ppubkey, err = p2pcrypto.UnmarshalPublicKey(bytespubkey)
+		if err != nil {
+			return nil, PUBKEY_UNKNOWN, fmt.Errorf("%w: %s", ErrUnknownKeyFormat, err)
+		}
+	}
+	return &Pubkey{kind: PUBKEY_LIBP2P, libp2p: p2ppubkey}, PUBKEY_LIBP2P, nil
+}
+
+// Kind returns the format the key was parsed from
+func (key *Pubkey) Kind() PubkeyKind {
+	return key.kind
+}
+
+// EthPubkey returns a copy of the secp256k1 key, a 0x address or a libp2p key of another type has none
+func (key *Pubkey) EthPubkey() (*ecdsa.PublicKey, error) {
+	switch key.kind {
+	case PUBKEY_ETH:
+		return &ecdsa.PublicKey{Curve: key.eth.Curve, X: new(big.Int).Set(key.eth.X), Y: new(big.Int).Set(key.eth.Y)}, nil
+	case PUBKEY_LIBP2P:
+		if key.libp2p.Type() != p2pcrypto.Secp256k1 {
+			return nil, fmt.Errorf("libp2p key of type %s is not a secp256k1 key", key.libp2p.Type())
+		}
+		raw, err := key.libp2p.Raw()
+		if err != nil {
+			return nil, err
+		}
+		return ethcrypto.DecompressPubkey(raw)
+	case PUBKEY_ETH_ADDRESS:
+		return nil, fmt.Errorf("the pubkey of address %s is only known from a signature", key.address.Hex())
+	}
+	return nil, fmt.Errorf("unknown pubkey kind %s", key.kind)
+}
+
+// EthAddress returns the checksummed 0x address of the key
+func (key *Pubkey) EthAddress() (string, error) {
+	if key.kind == PUBKEY_ETH_ADDRESS {
+		return key.address.Hex(), nil
+	}
+	ethpubkey, err := key.EthPubkey()
+	if err != nil {
+		return "", err
+	}
+	return ethcrypto.PubkeyToAddress(*ethpubkey).Hex(), nil
+}
+
+// EthEncoded returns the key in the base64 url encoded compressed form used by SenderPubkey
+func (key *Pubkey) EthEncoded() (string, error) {
+	ethpubkey, err := key.EthPubkey()
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(ethcrypto.CompressPubkey(ethpubkey)), nil
+}
+
+// Libp2pEncoded returns the key in the libp2p config encoded form
+func (key *Pubkey) Libp2pEncoded() (string, error) {
+	p2ppubkey := key.libp2p
+	if key.kind != PUBKEY_LIBP2P {
+		ethpubkey, err := key.EthPubkey()
+		if err != nil {
+			return "", err
+		}
+		if p2ppubkey, err = p2pcrypto.UnmarshalSecp256k1PublicKey(ethcrypto.CompressPubkey(ethpubkey)); err != nil {
+			return "", err
+		}
+	}
+	serialized, err := p2pcrypto.MarshalPublicKey(p2ppubkey)
+	if err != nil {
+		return "", err
+	}
+	return p2pcrypto.ConfigEncodeKey(serialized), nil
+}
+
+// Verify verifies the signature of hash by the key, it returns true and no error or false and an error
+// wrapping ErrBadSignature or ErrUnknownKeyFormat
+func (key *Pubkey) Verify(hash []byte, sig []byte) (bool, error) {
+	if len(sig) == 0 {
+		return false, fmt.Errorf("%w: not signed", ErrBadSignature)
+	}
+	switch key.kind {
+	case PUBKEY_ETH:
+		if !localcrypto.GetKeystore().EthVerifySign(hash, sig, key.eth) {
+			return false, ErrBadSignature
+		}
+		return true, nil
+	case PUBKEY_ETH_ADDRESS:
+		if len(sig) != ethcrypto.SignatureLength {
+			return false, fmt.Errorf("%w: invalid signature length %d", ErrBadSignature, len(sig))
+		}
+		//do not modify the signature of the caller
+		sig = append([]byte{}, sig...)
+		if sig[ethcrypto.RecoveryIDOffset] == 27 || sig[ethcrypto.RecoveryIDOffset] == 28 {
+			sig[ethcrypto.RecoveryIDOffset] -= 27
+		}
+		sigpubkey, err := ethcrypto.SigToPub(hash, sig)
+		if err != nil {
+			return false, fmt.Errorf("%w: %s", ErrBadSignature, err)
+		}
+		if !localcrypto.GetKeystore().EthVerifySign(hash, sig, sigpubkey) {
+			return false, ErrBadSignature
+		}
+		if ethcrypto.PubkeyToAddress(*sigpubkey) != key.address {
+			return false, fmt.Errorf("%w: sig not match with the 0x address", ErrBadSignature)
+		}
+		return true, nil
+	case PUBKEY_LIBP2P:
+		ok, err := key.libp2p.Verify(hash, sig)
+		if err != nil {
+			return false, fmt.Errorf("%w: %s", ErrBadSignature, err)
+		}
+		if !ok {
+			return false, ErrBadSignature
+		}
+		return true, nil
+	}
+	return false, fmt.Errorf("%w: pubkey kind %s", ErrUnknownKeyFormat, key.kind)
+}
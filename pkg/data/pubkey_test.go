@@ -0,0 +1,92 @@
+package data
+
+import (
+	"encoding/base64"
+	"testing"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
+	localcrypto "github.com/rumsystem/keystore/pkg/crypto"
+)
+
+func TestParsePubkey(t *testing.T) {
+	ethkey, _ := ethcrypto.GenerateKey()
+	encoded := base64.RawURLEncoding.EncodeToString(ethcrypto.CompressPubkey(&ethkey.PublicKey))
+	address := ethcrypto.PubkeyToAddress(ethkey.PublicKey).Hex()
+
+	key, kind, err := ParsePubkey(encoded)
+	if err != nil || kind != PUBKEY_ETH || key.Kind() != kind {
+		t.Fatalf("eth pubkey parsed as %s: %v", kind, err)
+	}
+	if addr, err := key.EthAddress(); err != nil || addr != address {
+		t.Errorf("eth pubkey converted to address %s: %v", addr, err)
+	}
+	libp2pencoded, err := key.Libp2pEncoded()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the libp2p form of an eth key converts back to it
+	key, kind, err = ParsePubkey(libp2pencoded)
+	if err != nil || kind != PUBKEY_LIBP2P {
+		t.Fatalf("libp2p pubkey parsed as %s: %v", kind, err)
+	}
+	if s, err := key.EthEncoded(); err != nil || s != encoded {
+		t.Errorf("libp2p pubkey converted to eth pubkey %s: %v", s, err)
+	}
+	if addr, err := key.EthAddress(); err != nil || addr != address {
+		t.Errorf("libp2p pubkey converted to address %s: %v", addr, err)
+	}
+
+	key, kind, err = ParsePubkey(address)
+	if err != nil || kind != PUBKEY_ETH_ADDRESS {
+		t.Fatalf("address parsed as %s: %v", kind, err)
+	}
+	if _, err := key.EthEncoded(); err == nil {
+		t.Errorf("address converted to a pubkey")
+	}
+
+	_, ed25519pubkey, _ := p2pcrypto.GenerateEd25519Key(nil)
+	serialized, _ := p2pcrypto.MarshalPublicKey(ed25519pubkey)
+	key, _, err = ParsePubkey(p2pcrypto.ConfigEncodeKey(serialized))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := key.EthAddress(); err == nil {
+		t.Errorf("ed25519 key converted to an address")
+	}
+
+	for _, invalid := range []string{"", "0x00112233445566778899aabbccddeeffgghhiijj", "not a pubkey"} {
+		if _, kind, err := ParsePubkey(invalid); err == nil || kind != PUBKEY_UNKNOWN {
+			t.Errorf("invalid pubkey %q parsed as %s", invalid, kind)
+		}
+	}
+}
+
+func TestVerifyBlockSignByAddress(t *testing.T) {
+	keystoreDir := t.TempDir()
+	groupitem := GetGroupItem()
+	addr, pubkey, err := GetKeyStorePubKey(groupitem.GroupId, keystoreDir)
+	if err != nil {
+		t.Fatalf("keystore new key err : %s", err)
+	}
+	ks := localcrypto.GetKeystore()
+	genesis, err := CreateGenesisBlockByEthKey(groupitem.GroupId, pubkey, ks, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := CreateBlockByEthKey(genesis, nil, addr, ks, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := IsBlockValid(block, genesis); !ok {
+		t.Errorf("block of 0x address producer is invalid: %v", err)
+	}
+
+	//the signature does not match another address
+	other, _ := ethcrypto.GenerateKey()
+	key, _, _ := ParsePubkey(ethcrypto.PubkeyToAddress(other.PublicKey).Hex())
+	if ok, _ := key.Verify(block.Hash, block.Signature); ok {
+		t.Errorf("block signature verified by another address")
+	}
+}
@@ -0,0 +1,68 @@
+package data
+
+import (
+	"container/list"
+	"sync"
+)
+
+const PUBKEY_CACHE_SIZE = 4096 //parsed sender and producer pubkeys kept by DefaultPubkeyResolver
+
+// PubkeyResolver parses pubkeys and keeps the most recently used ones in a LRU cache.
+// It is safe for concurrent use.
+type PubkeyResolver struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List //front is the most recently used
+}
+
+type pubkeyEntry struct {
+	pubkey string
+	key    *Pubkey
+}
+
+// DefaultPubkeyResolver is used by VerifyTrx and VerifyBlockSign
+var DefaultPubkeyResolver = NewPubkeyResolver(PUBKEY_CACHE_SIZE)
+
+// NewPubkeyResolver caches up to size parsed pubkeys, nothing is cached if size is 0
+func NewPubkeyResolver(size int) *PubkeyResolver {
+	return &PubkeyResolver{size: size, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// Resolve returns the parsed pubkey, from the cache when it was resolved before: the same immutable Pubkey
+// is returned to every caller. Invalid pubkeys are not cached.
+func (resolver *PubkeyResolver) Resolve(pubkey string) (*Pubkey, error) {
+	resolver.mu.Lock()
+	if elem, ok := resolver.entries[pubkey]; ok {
+		resolver.order.MoveToFront(elem)
+		resolver.mu.Unlock()
+		return elem.Value.(*pubkeyEntry).key, nil
+	}
+	resolver.mu.Unlock()
+
+	key, _, err := ParsePubkey(pubkey)
+	if err != nil || resolver.size <= 0 {
+		return key, err
+	}
+
+	resolver.mu.Lock()
+	defer resolver.mu.Unlock()
+	if elem, ok := resolver.entries[pubkey]; ok { //resolved concurrently
+		resolver.order.MoveToFront(elem)
+		return elem.Value.(*pubkeyEntry).key, nil
+	}
+	resolver.entries[pubkey] = resolver.order.PushFront(&pubkeyEntry{pubkey: pubkey, key: key})
+	for resolver.order.Len() > resolver.size {
+		oldest := resolver.order.Back()
+		resolver.order.Remove(oldest)
+		delete(resolver.entries, oldest.Value.(*pubkeyEntry).pubkey)
+	}
+	return key, nil
+}
+
+// Len returns the number of cached pubkeys
+func (resolver *PubkeyResolver) Len() int {
+	resolver.mu.Lock()
+	defer resolver.mu.Unlock()
+	return resolver.order.Len()
+}
@@ -0,0 +1,125 @@
+package data
+
+import (
+	"encoding/base64"
+	"testing"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
+)
+
+func getTestPubkeys(t testing.TB, count int) []string {
+	var pubkeys []string
+	for i := 0; i < count; i++ {
+		key, err := ethcrypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubkeys = append(pubkeys, base64.RawURLEncoding.EncodeToString(ethcrypto.CompressPubkey(&key.PublicKey)))
+	}
+	return pubkeys
+}
+
+func TestPubkeyResolver(t *testing.T) {
+	ethkey, _ := ethcrypto.GenerateKey()
+	_, p2ppubkey, err := p2pcrypto.GenerateSecp256k1Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serialized, _ := p2pcrypto.MarshalPublicKey(p2ppubkey)
+
+	resolver := NewPubkeyResolver(2)
+	for pubkey, kind := range map[string]PubkeyKind{
+		base64.RawURLEncoding.EncodeToString(ethcrypto.CompressPubkey(&ethkey.PublicKey)): PUBKEY_ETH,
+		ethcrypto.PubkeyToAddress(ethkey.PublicKey).Hex():                                 PUBKEY_ETH_ADDRESS,
+		p2pcrypto.ConfigEncodeKey(serialized):                                             PUBKEY_LIBP2P,
+	} {
+		key, err := resolver.Resolve(pubkey)
+		if err != nil || key.Kind() != kind {
+			t.Errorf("pubkey %s resolved as %v, %v, expect %s", pubkey, key, err, kind)
+		}
+	}
+	if _, err := resolver.Resolve("not a pubkey"); err == nil {
+		t.Errorf("invalid pubkey resolved")
+	}
+	if resolver.Len() != 2 {
+		t.Errorf("resolver caches %d pubkeys, expect 2", resolver.Len())
+	}
+
+	pubkeys := getTestPubkeys(t, 3)
+	first, _ := resolver.Resolve(pubkeys[0])
+	resolver.Resolve(pubkeys[1])
+	if again, _ := resolver.Resolve(pubkeys[0]); again != first {
+		t.Errorf("cached pubkey parsed again")
+	}
+	//pubkeys[1] is the least recently used
+	resolver.Resolve(pubkeys[2])
+	if _, ok := resolver.entries[pubkeys[1]]; ok {
+		t.Errorf("least recently used pubkey not evicted")
+	}
+	if _, ok := resolver.entries[pubkeys[0]]; !ok {
+		t.Errorf("recently used pubkey evicted")
+	}
+}
+
+func benchmarkVerifyTrx(b *testing.B, resolver *PubkeyResolver) {
+	trxs := getTestTrxs(b, 64)
+	defaultresolver := DefaultPubkeyResolver
+	DefaultPubkeyResolver = resolver
+	defer func() { DefaultPubkeyResolver = defaultresolver }()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if ok, err := VerifyTrx(trxs[n%len(trxs)]); !ok || err != nil {
+			b.Fatalf("verify trx failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkVerifyTrxUncached(b *testing.B) {
+	benchmarkVerifyTrx(b, NewPubkeyResolver(0))
+}
+
+func BenchmarkVerifyTrxCached(b *testing.B) {
+	benchmarkVerifyTrx(b, NewPubkeyResolver(PUBKEY_CACHE_SIZE))
+}
+
+func BenchmarkParsePubkey(b *testing.B) {
+	pubkeys := getTestPubkeys(b, 256)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, _, err := ParsePubkey(pubkeys[n%len(pubkeys)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPubkeyResolverResolve(b *testing.B) {
+	pubkeys := getTestPubkeys(b, 256)
+	resolver := NewPubkeyResolver(PUBKEY_CACHE_SIZE)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := resolver.Resolve(pubkeys[n%len(pubkeys)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestPubkeyResolverShared(t *testing.T) {
+	resolver := NewPubkeyResolver(1)
+	pubkey := getTestPubkeys(t, 1)[0]
+	key, err := resolver.Resolve(pubkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ethpubkey, err := key.EthPubkey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ethpubkey.X.SetInt64(1)
+	ethpubkey.Y = nil
+
+	cached, _ := resolver.Resolve(pubkey)
+	if encoded, err := cached.EthEncoded(); err != nil || encoded != pubkey {
+		t.Errorf("cached pubkey modified by a caller: %s, %v", encoded, err)
+	}
+}
@@ -0,0 +1,381 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// SchemaItem.Type for a rule applied to every POST content, whatever its message type
+const SCHEMA_TYPE_ANY = "*"
+
+// SchemaEngine keeps the active schema rules of a group and validates POST content against them.
+// SchemaItem.Type is the full name of the content message ("quorum.pb.Activity", "quorum.pb.Object"...)
+// or SCHEMA_TYPE_ANY, SchemaItem.Rule is a JSON Schema applied to the protojson form of the content
+// (proto field names are used as property names).
+// Supported keywords: type, enum, const, required, properties, additionalProperties, minLength,
+// maxLength, pattern, minimum, maximum, items, minItems and maxItems.
+type SchemaEngine struct {
+	groupId string
+	mu      sync.RWMutex
+	rules   map[string]*jsonSchema
+}
+
+type jsonSchema struct {
+	Schema      string `json:"$schema"`
+	Id          string `json:"$id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+
+	Type                 json.RawMessage        `json:"type"`
+	Enum                 []interface{}          `json:"enum"`
+	Const                *json.RawMessage       `json:"const"`
+	Required             []string               `json:"required"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	Pattern              string                 `json:"pattern"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	Items                *jsonSchema            `json:"items"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+
+	types   []string
+	pattern *regexp.Regexp
+}
+
+var schemaJSONTypes = map[string]bool{"object": true, "array": true, "string": true, "number": true, "integer": true, "boolean": true, "null": true}
+
+func NewSchemaEngine(groupId string) *SchemaEngine {
+	return &SchemaEngine{groupId: groupId, rules: make(map[string]*jsonSchema)}
+}
+
+// Apply adds or removes a rule, items should be applied in block order
+func (engine *SchemaEngine) Apply(item *quorumpb.SchemaItem) error {
+	if item.GroupId != engine.groupId {
+		return fmt.Errorf("schema item for group %s, expect group %s", item.GroupId, engine.groupId)
+	}
+	if item.Type == "" {
+		return fmt.Errorf("schema item type must not be empty")
+	}
+
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	switch item.Action {
+	case quorumpb.ActionType_ADD:
+		rule, err := compileSchemaRule(item.Rule)
+		if err != nil {
+			return fmt.Errorf("invalid schema rule for type %s: %s", item.Type, err)
+		}
+		engine.rules[item.Type] = rule
+	case quorumpb.ActionType_REMOVE:
+		delete(engine.rules, item.Type)
+	default:
+		return fmt.Errorf("unknown schema item action %s", item.Action)
+	}
+	return nil
+}
+
+// Types returns the content types which have an active rule
+func (engine *SchemaEngine) Types() []string {
+	engine.mu.RLock()
+	defer engine.mu.RUnlock()
+	types := make([]string, 0, len(engine.rules))
+	for t := range engine.rules {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Validate checks content against the rule of its type and the SCHEMA_TYPE_ANY rule
+func (engine *SchemaEngine) Validate(content proto.Message) error {
+	typename := string(content.ProtoReflect().Descriptor().FullName())
+
+	engine.mu.RLock()
+	anyrule := engine.rules[SCHEMA_TYPE_ANY]
+	typerule := engine.rules[typename]
+	engine.mu.RUnlock()
+
+	if anyrule == nil && typerule == nil {
+		return nil
+	}
+
+	jsonbytes, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(content)
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(jsonbytes))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+	value = schemaNumbers(value, content.ProtoReflect().Descriptor())
+
+	for _, rule := range []*jsonSchema{anyrule, typerule} {
+		if rule == nil {
+			continue
+		}
+		if err := rule.validate("$", value); err != nil {
+			return fmt.Errorf("content of type %s violates group schema: %s", typename, err)
+		}
+	}
+	return nil
+}
+
+// ValidatePostData decodes the (decrypted) data of a received POST trx and validates it
+func (engine *SchemaEngine) ValidatePostData(trxid string, data []byte) error {
+	content, _, err := DecodePostContent(trxid, data)
+	if err != nil {
+		return err
+	}
+	return engine.Validate(content)
+}
+
+// protojson renders 64-bit integers as JSON strings, schemaNumbers turns them back into numbers
+// so that type, minimum and maximum apply to them
+func schemaNumbers(value interface{}, desc protoreflect.MessageDescriptor) interface{} {
+	obj, ok := value.(map[string]interface{})
+	if !ok || strings.HasPrefix(string(desc.FullName()), "google.protobuf.") {
+		return value
+	}
+	fields := desc.Fields()
+	for name, v := range obj {
+		if field := fields.ByName(protoreflect.Name(name)); field != nil {
+			obj[name] = schemaFieldNumbers(v, field)
+		}
+	}
+	return obj
+}
+
+func schemaFieldNumbers(value interface{}, field protoreflect.FieldDescriptor) interface{} {
+	switch {
+	case field.IsMap():
+		if m, ok := value.(map[string]interface{}); ok {
+			for k, v := range m {
+				m[k] = schemaFieldNumbers(v, field.MapValue())
+			}
+		}
+		return value
+	case field.IsList():
+		if l, ok := value.([]interface{}); ok {
+			for i, v := range l {
+				l[i] = schemaValueNumbers(v, field)
+			}
+		}
+		return value
+	}
+	return schemaValueNumbers(value, field)
+}
+
+func schemaValueNumbers(value interface{}, field protoreflect.FieldDescriptor) interface{} {
+	switch field.Kind() {
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if s, ok := value.(string); ok {
+			return json.Number(s)
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return schemaNumbers(value, field.Message())
+	}
+	return value
+}
+
+func compileSchemaRule(rule string) (*jsonSchema, error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(rule)))
+	decoder.DisallowUnknownFields()
+	schema := &jsonSchema{}
+	if err := decoder.Decode(schema); err != nil {
+		return nil, err
+	}
+	if err := schema.compile(); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (schema *jsonSchema) compile() error {
+	if len(schema.Type) > 0 {
+		var single string
+		if err := json.Unmarshal(schema.Type, &single); err == nil {
+			schema.types = []string{single}
+		} else if err := json.Unmarshal(schema.Type, &schema.types); err != nil {
+			return fmt.Errorf("type must be a string or an array of strings")
+		}
+		for _, t := range schema.types {
+			if !schemaJSONTypes[t] {
+				return fmt.Errorf("unknown type %s", t)
+			}
+		}
+	}
+	if schema.Pattern != "" {
+		pattern, err := regexp.Compile(schema.Pattern)
+		if err != nil {
+			return err
+		}
+		schema.pattern = pattern
+	}
+	for name, prop := range schema.Properties {
+		if err := prop.compile(); err != nil {
+			return fmt.Errorf("properties.%s: %s", name, err)
+		}
+	}
+	if schema.Items != nil {
+		if err := schema.Items.compile(); err != nil {
+			return fmt.Errorf("items: %s", err)
+		}
+	}
+	return nil
+}
+
+func (schema *jsonSchema) validate(path string, value interface{}) error {
+	if len(schema.types) > 0 {
+		matched := false
+		for _, t := range schema.types {
+			if jsonValueIsType(value, t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: expect type %v", path, schema.types)
+		}
+	}
+
+	if schema.Const != nil && !jsonValueEqual(value, *schema.Const) {
+		return fmt.Errorf("%s: value must be %s", path, string(*schema.Const))
+	}
+
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, e := range schema.Enum {
+			ebytes, _ := json.Marshal(e)
+			if jsonValueEqual(value, ebytes) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value not in enum", path)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s: missing required property %s", path, name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := schema.Properties[name]
+			if !ok {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					return fmt.Errorf("%s: property %s is not allowed", path, name)
+				}
+				continue
+			}
+			if err := prop.validate(path+"."+name, v[name]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if schema.MinItems != nil && len(v) < *schema.MinItems {
+			return fmt.Errorf("%s: expect at least %d items", path, *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(v) > *schema.MaxItems {
+			return fmt.Errorf("%s: expect at most %d items", path, *schema.MaxItems)
+		}
+		if schema.Items != nil {
+			for i, item := range v {
+				if err := schema.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			return fmt.Errorf("%s: expect at least %d characters", path, *schema.MinLength)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			return fmt.Errorf("%s: expect at most %d characters", path, *schema.MaxLength)
+		}
+		if schema.pattern != nil && !schema.pattern.MatchString(v) {
+			return fmt.Errorf("%s: value does not match pattern %s", path, schema.Pattern)
+		}
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return fmt.Errorf("%s: invalid number %s", path, v)
+		}
+		if schema.Minimum != nil && f < *schema.Minimum {
+			return fmt.Errorf("%s: expect minimum %v", path, *schema.Minimum)
+		}
+		if schema.Maximum != nil && f > *schema.Maximum {
+			return fmt.Errorf("%s: expect maximum %v", path, *schema.Maximum)
+		}
+	}
+	return nil
+}
+
+func jsonValueIsType(value interface{}, t string) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return t == "object"
+	case []interface{}:
+		return t == "array"
+	case string:
+		return t == "string"
+	case bool:
+		return t == "boolean"
+	case nil:
+		return t == "null"
+	case json.Number:
+		if t == "number" {
+			return true
+		}
+		if t == "integer" {
+			//uint64 values are over the range of Int64
+			_, err := v.Float64()
+			return err == nil && !strings.ContainsAny(v.String(), ".eE")
+		}
+	}
+	return false
+}
+
+func jsonValueEqual(value interface{}, expect []byte) bool {
+	var e interface{}
+	decoder := json.NewDecoder(bytes.NewReader(expect))
+	decoder.UseNumber()
+	if err := decoder.Decode(&e); err != nil {
+		return false
+	}
+	if vn, ok := value.(json.Number); ok {
+		if en, ok := e.(json.Number); ok {
+			vf, err1 := vn.Float64()
+			ef, err2 := en.Float64()
+			return err1 == nil && err2 == nil && vf == ef
+		}
+		return false
+	}
+	return reflect.DeepEqual(value, e)
+}
@@ -0,0 +1,144 @@
+package data
+
+import (
+	"testing"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+)
+
+const testNoteRule = `{
+	"type": "object",
+	"required": ["type", "object"],
+	"properties": {
+		"type": {"enum": ["Add", "Update"]},
+		"object": {
+			"type": "object",
+			"required": ["content"],
+			"properties": {
+				"type": {"const": "Note"},
+				"content": {"type": "string", "minLength": 1, "maxLength": 20}
+			}
+		}
+	}
+}`
+
+func TestSchemaEngine(t *testing.T) {
+	groupitem := GetGroupItem()
+	engine := NewSchemaEngine(groupitem.GroupId)
+	err := engine.Apply(&quorumpb.SchemaItem{GroupId: groupitem.GroupId, Type: "quorum.pb.Activity", Rule: testNoteRule, Action: quorumpb.ActionType_ADD})
+	if err != nil {
+		t.Fatalf("apply schema item err: %s", err)
+	}
+
+	valid := &quorumpb.Activity{Type: "Add", Object: &quorumpb.Object{Type: "Note", Content: "test content"}}
+	if err := engine.Validate(valid); err != nil {
+		t.Errorf("valid content rejected: %s", err)
+	}
+
+	invalids := []*quorumpb.Activity{
+		{Type: "Delete", Object: &quorumpb.Object{Type: "Note", Content: "test content"}},
+		{Type: "Add", Object: &quorumpb.Object{Type: "Image", Content: "test content"}},
+		{Type: "Add", Object: &quorumpb.Object{Type: "Note", Content: "a test content longer than the limit"}},
+		{Type: "Add", Object: &quorumpb.Object{Type: "Note"}},
+		{Type: "Add"},
+	}
+	for i, content := range invalids {
+		if err := engine.Validate(content); err == nil {
+			t.Errorf("invalid content %d accepted", i)
+		}
+	}
+
+	//rules only apply to their own type
+	if err := engine.Validate(&quorumpb.Object{Type: "Note"}); err != nil {
+		t.Errorf("content of other type rejected: %s", err)
+	}
+
+	data, err := quorumpb.ContentToBytes(invalids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.ValidatePostData("trxid", data); err == nil {
+		t.Errorf("invalid post data accepted")
+	}
+
+	err = engine.Apply(&quorumpb.SchemaItem{GroupId: groupitem.GroupId, Type: "quorum.pb.Activity", Action: quorumpb.ActionType_REMOVE})
+	if err != nil {
+		t.Fatalf("remove schema item err: %s", err)
+	}
+	if err := engine.Validate(invalids[0]); err != nil {
+		t.Errorf("content rejected after schema removed: %s", err)
+	}
+}
+
+func TestSchemaEngineInvalidRule(t *testing.T) {
+	groupitem := GetGroupItem()
+	engine := NewSchemaEngine(groupitem.GroupId)
+	rules := []string{
+		`not json`,
+		`{"type": "unknowntype"}`,
+		`{"pattern": "("}`,
+		`{"oneOf": []}`,
+	}
+	for _, rule := range rules {
+		err := engine.Apply(&quorumpb.SchemaItem{GroupId: groupitem.GroupId, Type: SCHEMA_TYPE_ANY, Rule: rule, Action: quorumpb.ActionType_ADD})
+		if err == nil {
+			t.Errorf("invalid rule %s accepted", rule)
+		}
+	}
+
+	err := engine.Apply(&quorumpb.SchemaItem{GroupId: "another group", Type: SCHEMA_TYPE_ANY, Rule: `{}`})
+	if err == nil {
+		t.Errorf("schema item of another group accepted")
+	}
+}
+
+func TestGetPostAnyTrxWithSchema(t *testing.T) {
+	keystoreDir := t.TempDir()
+	tn := &TestNonce{}
+	trxFactory := &TrxFactory{}
+	groupitem := GetGroupItem()
+	trxFactory.Init("1.0.0", groupitem, "default", tn)
+	_, pubkey, err := GetKeyStorePubKey(groupitem.GroupId, keystoreDir)
+	if err != nil {
+		t.Fatalf("keystore new key err : %s", err)
+	}
+	groupitem.UserSignPubkey = pubkey
+
+	engine := NewSchemaEngine(groupitem.GroupId)
+	engine.Apply(&quorumpb.SchemaItem{GroupId: groupitem.GroupId, Type: "quorum.pb.Activity", Rule: testNoteRule, Action: quorumpb.ActionType_ADD})
+	trxFactory.SetSchemaEngine(engine)
+
+	if _, err := trxFactory.GetPostAnyTrx("", &quorumpb.Activity{Type: "Add"}); err == nil {
+		t.Errorf("post with invalid content should fail")
+	}
+	postobj := &quorumpb.Activity{Type: "Add", Object: &quorumpb.Object{Type: "Note", Content: "test content"}}
+	if _, err := trxFactory.GetPostAnyTrx("", postobj); err != nil {
+		t.Errorf("post with valid content failed: %s", err)
+	}
+}
+
+func TestSchemaEngineInt64(t *testing.T) {
+	groupitem := GetGroupItem()
+	engine := NewSchemaEngine(groupitem.GroupId)
+	rule := `{"properties": {
+		"size": {"type": "integer", "minimum": 1, "maximum": 1000},
+		"chunks": {"items": {"properties": {"size": {"type": "integer", "maximum": 500}}}}
+	}}`
+	if err := engine.Apply(&quorumpb.SchemaItem{GroupId: groupitem.GroupId, Type: "quorum.pb.FileManifest", Rule: rule, Action: quorumpb.ActionType_ADD}); err != nil {
+		t.Fatalf("apply schema item err: %s", err)
+	}
+
+	valid := &quorumpb.FileManifest{Id: "file", Size: 1000, Chunks: []*quorumpb.FileChunkInfo{{Index: 0, Size: 500}, {Index: 1, Size: 500}}}
+	if err := engine.Validate(valid); err != nil {
+		t.Errorf("valid manifest rejected: %s", err)
+	}
+	for i, manifest := range []*quorumpb.FileManifest{
+		{Id: "file", Size: 1001},
+		{Id: "file", Size: -1},
+		{Id: "file", Size: 600, Chunks: []*quorumpb.FileChunkInfo{{Index: 0, Size: 600}}},
+	} {
+		if err := engine.Validate(manifest); err == nil {
+			t.Errorf("manifest %d with an int64 out of range accepted", i)
+		}
+	}
+}
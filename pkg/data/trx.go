@@ -1,16 +1,11 @@
 package data
 
 import (
-	"encoding/base64"
 	"encoding/hex"
 	"fmt"
-	"strings"
 	"time"
 
-	"github.com/ethereum/go-ethereum/crypto"
-	ethcrypto "github.com/ethereum/go-ethereum/crypto"
 	guuid "github.com/google/uuid"
-	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
 	localcrypto "github.com/rumsystem/keystore/pkg/crypto"
 	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
 	"google.golang.org/protobuf/proto"
@@ -62,6 +57,7 @@ func CreateTrxWithoutSign(nodename string, version string, groupItem *quorumpb.G
 		if err != nil {
 			return &trx, []byte(""), err
 		}
+		trx.CipherKeyEpoch = groupItem.CipherKeyEpoch
 	}
 
 	trx.Data = encryptdData
@@ -83,8 +79,15 @@ func CreateTrxByEthKey(nodename string, version string, groupItem *quorumpb.Grou
 	if err != nil {
 		return trx, err
 	}
+	err = signTrx(trx, hash, groupItem, keyalias)
+	return trx, err
+}
+
+// sign with the key named by the group id, or with the key of keyalias
+func signTrx(trx *quorumpb.Trx, hash []byte, groupItem *quorumpb.GroupItem, keyalias string) error {
 	ks := localcrypto.GetKeystore()
 	var signature []byte
+	var err error
 	if keyalias == "" {
 		keyname := groupItem.GroupId
 		signature, err = ks.EthSignByKeyName(keyname, hash)
@@ -93,11 +96,10 @@ func CreateTrxByEthKey(nodename string, version string, groupItem *quorumpb.Grou
 	}
 
 	if err != nil {
-		return trx, err
+		return err
 	}
 	trx.SenderSign = signature
-	return trx, nil
-
+	return nil
 }
 
 // set TimeStamp and Expired for trx
@@ -109,69 +111,47 @@ func UpdateTrxTimeLimit(trx *quorumpb.Trx) {
 	trx.Expired = timein.UnixNano()
 }
 
-func VerifyTrx(trx *quorumpb.Trx) (bool, error) {
-	//clone trxMsg to verify
+// TrxHash returns the hash signed by the sender of a trx, computed without SenderSign and ResendCount
+func TrxHash(trx *quorumpb.Trx) ([]byte, error) {
 	clonetrxmsg := &quorumpb.Trx{
-		TrxId:        trx.TrxId,
-		Type:         trx.Type,
-		GroupId:      trx.GroupId,
-		SenderPubkey: trx.SenderPubkey,
-		Nonce:        trx.Nonce,
-		Data:         trx.Data,
-		TimeStamp:    trx.TimeStamp,
-		Version:      trx.Version,
-		Expired:      trx.Expired}
+		TrxId:          trx.TrxId,
+		Type:           trx.Type,
+		GroupId:        trx.GroupId,
+		SenderPubkey:   trx.SenderPubkey,
+		Nonce:          trx.Nonce,
+		Data:           trx.Data,
+		TimeStamp:      trx.TimeStamp,
+		Version:        trx.Version,
+		Expired:        trx.Expired,
+		CipherKeyEpoch: trx.CipherKeyEpoch,
+		DirectMessage:  trx.DirectMessage}
 
 	bytes, err := proto.Marshal(clonetrxmsg)
 	if err != nil {
-		return false, err
-	}
-	hash := localcrypto.Hash(bytes)
-	ks := localcrypto.GetKeystore()
-
-	if len(trx.SenderPubkey) == 42 && trx.SenderPubkey[:2] == "0x" { //try 0x address
-		//try verify 0x address
-		sig := trx.SenderSign
-		if sig[crypto.RecoveryIDOffset] == 27 || sig[crypto.RecoveryIDOffset] == 28 {
-			sig[crypto.RecoveryIDOffset] -= 27
-		}
-		sigpubkey, err := ethcrypto.SigToPub(hash, sig)
-		if err == nil {
-			r := ks.EthVerifySign(hash, trx.SenderSign, sigpubkey)
-			if r == true {
-				addressfrompubkey := ethcrypto.PubkeyToAddress(*sigpubkey).Hex()
-				if strings.ToLower(addressfrompubkey) == strings.ToLower(trx.SenderPubkey) {
-					return true, nil
-				} else {
-					return false, fmt.Errorf("sig not match with the 0x address")
-				}
-			}
-		}
+		return nil, err
 	}
+	return localcrypto.Hash(bytes), nil
+}
 
-	bytespubkey, err := base64.RawURLEncoding.DecodeString(trx.SenderPubkey)
-
-	if err == nil { //try eth key
-		ethpubkey, err := ethcrypto.DecompressPubkey(bytespubkey)
-		if err == nil {
-			r := ks.EthVerifySign(hash, trx.SenderSign, ethpubkey)
-			return r, nil
-		}
-	}
-	//create pubkey
-	serializedpub, err := p2pcrypto.ConfigDecodeKey(trx.SenderPubkey)
+func VerifyTrx(trx *quorumpb.Trx) (bool, error) {
+	hash, err := TrxHash(trx)
 	if err != nil {
 		return false, err
 	}
-
-	pubkey, err := p2pcrypto.UnmarshalPublicKey(serializedpub)
+	pubkey, err := DefaultPubkeyResolver.Resolve(trx.SenderPubkey)
 	if err != nil {
-		pubkey, err = p2pcrypto.UnmarshalPublicKey(bytespubkey)
-		if err != nil {
-			return false, err
-		}
+		return false, trxVerifyError(trx.TrxId, err)
+	}
+	if ok, err := pubkey.Verify(hash, trx.SenderSign); !ok {
+		return false, trxVerifyError(trx.TrxId, err)
 	}
+	return true, nil
+}
 
-	verify, err := pubkey.Verify(hash, trx.SenderSign)
-	return verify, err
+// VerifyTrxExpiry returns an error wrapping ErrExpired if the trx is expired at now
+func VerifyTrxExpiry(trx *quorumpb.Trx, now time.Time) error {
+	if trx.Expired != 0 && now.UnixNano() > trx.Expired {
+		return trxVerifyError(trx.TrxId, fmt.Errorf("%w at %s", ErrExpired, time.Unix(0, trx.Expired).UTC().Format(time.RFC3339)))
+	}
+	return nil
 }
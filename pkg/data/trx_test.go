@@ -86,3 +86,51 @@ func TestVerifyTrxByAddress(t *testing.T) {
 		t.Errorf("verify trx sig with pubkey error:%s", err)
 	}
 }
+
+func TestGetPostAnyTrxWithRecipientResolver(t *testing.T) {
+	keystoreDir := t.TempDir()
+	tn := &TestNonce{}
+	trxFactory := &TrxFactory{}
+	groupitem := GetGroupItem()
+	groupitem.EncryptType = quorumpb.GroupEncryptType_PRIVATE
+	trxFactory.Init("1.0.0", groupitem, "default", tn)
+	_, pubkey, err := GetKeyStorePubKey(groupitem.GroupId, keystoreDir)
+	if err != nil {
+		t.Fatalf("keystore new key err : %s", err)
+	}
+	groupitem.UserSignPubkey = pubkey
+	groupitem.OwnerPubKey = pubkey
+
+	ks := localcrypto.GetKeystore()
+	membername := groupitem.GroupId + "_member"
+	if _, err := ks.NewKeyWithDefaultPassword(membername, localcrypto.Encrypt); err != nil {
+		t.Fatalf("keystore new encrypt key err : %s", err)
+	}
+	memberpubkey, err := ks.(*localcrypto.DirKeyStore).GetEncodedPubkey(membername, localcrypto.Encrypt)
+	if err != nil {
+		t.Fatalf("get encrypt pubkey err : %s", err)
+	}
+
+	obj := &quorumpb.Object{Type: "Note", Content: "test content"}
+	if _, err := trxFactory.GetPostAnyTrx("", obj); err == nil {
+		t.Errorf("post to private group without recipients should fail")
+	}
+
+	state := NewMembershipState(groupitem.GroupId, pubkey)
+	state.ApplyAnnounceItem(1, "member", &quorumpb.AnnounceItem{GroupId: groupitem.GroupId, SignPubkey: "member", EncryptPubkey: memberpubkey, Type: quorumpb.AnnounceType_AS_USER_ENCRYPT})
+	state.ApplyAnnounceItem(2, pubkey, &quorumpb.AnnounceItem{GroupId: groupitem.GroupId, SignPubkey: "member", Type: quorumpb.AnnounceType_AS_USER_ENCRYPT, OwnerPubkey: pubkey, Result: quorumpb.ApproveType_APPROVED})
+	trxFactory.SetRecipientResolver(state)
+
+	trx, err := trxFactory.GetPostAnyTrx("", obj)
+	if err != nil {
+		t.Fatalf("post to private group err: %s", err)
+	}
+	data, err := ks.Decrypt(membername, trx.Data)
+	if err != nil {
+		t.Fatalf("member decrypt post err: %s", err)
+	}
+	content, _, err := quorumpb.BytesToMessage(trx.TrxId, data)
+	if err != nil || content.(*quorumpb.Object).Content != obj.Content {
+		t.Errorf("decrypted content mismatch: %v", err)
+	}
+}
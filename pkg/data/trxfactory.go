@@ -2,7 +2,10 @@ package data
 
 import (
 	"encoding/binary"
-	"errors"
+	"fmt"
+	"time"
+
+	localcrypto "github.com/rumsystem/keystore/pkg/crypto"
 	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
 	"google.golang.org/protobuf/proto"
 )
@@ -13,12 +16,21 @@ type TrxFactory struct {
 	groupItem  *quorumpb.GroupItem
 	chainNonce ChainNonce
 	version    string
+
+	schemaEngine      *SchemaEngine
+	recipientResolver RecipientResolver
+	activityValidator *ActivityValidator
 }
 
 type ChainNonce interface {
 	GetNextNouce(groupId string, prefix ...string) (nonce uint64, err error)
 }
 
+// RecipientResolver gives the encrypt pubkeys of the current approved members of a private group
+type RecipientResolver interface {
+	GetEncryptPubkeys(groupId string) ([]string, error)
+}
+
 func (factory *TrxFactory) Init(version string, groupItem *quorumpb.GroupItem, nodename string, chainnonce ChainNonce) {
 	factory.groupItem = groupItem
 	factory.groupId = groupItem.GroupId
@@ -27,6 +39,22 @@ func (factory *TrxFactory) Init(version string, groupItem *quorumpb.GroupItem, n
 	factory.version = version
 }
 
+// SetSchemaEngine enables the group schema check on POST content before it is signed
+func (factory *TrxFactory) SetSchemaEngine(engine *SchemaEngine) {
+	factory.schemaEngine = engine
+}
+
+// SetActivityValidator enables the activity semantic check on POST content before it is signed
+func (factory *TrxFactory) SetActivityValidator(validator *ActivityValidator) {
+	factory.activityValidator = validator
+}
+
+// SetRecipientResolver makes GetPostAnyTrx encrypt private group POSTs to the resolved members
+// when no encryptto pubkeys are given
+func (factory *TrxFactory) SetRecipientResolver(resolver RecipientResolver) {
+	factory.recipientResolver = resolver
+}
+
 func (factory *TrxFactory) CreateTrxByEthKey(msgType quorumpb.TrxType, data []byte, keyalias string, encryptto ...[]string) (*quorumpb.Trx, error) {
 	nonce, err := factory.chainNonce.GetNextNouce(factory.groupItem.GroupId, factory.nodename)
 	if err != nil {
@@ -69,6 +97,14 @@ func (factory *TrxFactory) GetRegUserTrx(keyalias string, item *quorumpb.UserIte
 	return factory.CreateTrxByEthKey(quorumpb.TrxType_USER, encodedcontent, keyalias)
 }
 
+func (factory *TrxFactory) GetUpdStakeTrx(keyalias string, item *quorumpb.StakeItem) (*quorumpb.Trx, error) {
+	encodedcontent, err := proto.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	return factory.CreateTrxByEthKey(quorumpb.TrxType_STAKE, encodedcontent, keyalias)
+}
+
 func (factory *TrxFactory) GetAnnounceTrx(keyalias string, item *quorumpb.AnnounceItem) (*quorumpb.Trx, error) {
 	encodedcontent, err := proto.Marshal(item)
 	if err != nil {
@@ -87,6 +123,43 @@ func (factory *TrxFactory) GetUpdSchemaTrx(keyalias string, item *quorumpb.Schem
 	return factory.CreateTrxByEthKey(quorumpb.TrxType_SCHEMA, encodedcontent, keyalias)
 }
 
+// GetCipherKeyTrx announces cipherKey as the group cipher key of the next epoch, the key is encrypted
+// to encryptto, or to the members given by the RecipientResolver if encryptto is empty
+func (factory *TrxFactory) GetCipherKeyTrx(keyalias string, cipherKey string, encryptto ...[]string) (*quorumpb.Trx, error) {
+	var recipients []string
+	if len(encryptto) == 1 {
+		recipients = encryptto[0]
+	} else if factory.recipientResolver != nil {
+		var err error
+		recipients, err = factory.resolveRecipients()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("must have encrypt pubkeys for new cipher key of group %s", factory.groupId)
+	}
+
+	ks := localcrypto.GetKeystore()
+	encryptedKey, err := ks.EncryptTo(recipients, []byte(cipherKey))
+	if err != nil {
+		return nil, err
+	}
+
+	item := &quorumpb.CipherKeyItem{
+		GroupId:      factory.groupId,
+		Epoch:        factory.groupItem.CipherKeyEpoch + 1,
+		EncryptedKey: encryptedKey,
+		OwnerPubkey:  factory.groupItem.UserSignPubkey,
+		TimeStamp:    time.Now().UnixNano(),
+	}
+	encodedcontent, err := proto.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	return factory.CreateTrxByEthKey(quorumpb.TrxType_CIPHER_KEY, encodedcontent, keyalias)
+}
+
 func (factory *TrxFactory) GetReqBlockRespTrx(keyalias string, requester string, block *quorumpb.Block, result quorumpb.ReqBlkResult) (*quorumpb.Trx, error) {
 	var reqBlockRespItem quorumpb.ReqBlockResp
 	reqBlockRespItem.Result = result
@@ -139,23 +212,88 @@ func (factory *TrxFactory) GetReqBlockBackwardTrx(keyalias string, block *quorum
 }
 
 func (factory *TrxFactory) GetBlockProducedTrx(keyalias string, blk *quorumpb.Block) (*quorumpb.Trx, error) {
-	encodedcontent, err := proto.Marshal(blk)
+	encodedcontent, err := proto.Marshal(&quorumpb.BlockProduced{BlockItem: blk, Version: BLOCK_MSG_VERSION})
 	if err != nil {
 		return nil, err
 	}
 	return CreateTrxByEthKey(factory.nodename, factory.version, factory.groupItem, quorumpb.TrxType_BLOCK_PRODUCED, int64(0), encodedcontent, keyalias)
 }
 
+func (factory *TrxFactory) GetBlockSyncedTrx(keyalias string, blk *quorumpb.Block) (*quorumpb.Trx, error) {
+	encodedcontent, err := proto.Marshal(&quorumpb.BlockSynced{BlockItem: blk, Version: BLOCK_MSG_VERSION})
+	if err != nil {
+		return nil, err
+	}
+	return CreateTrxByEthKey(factory.nodename, factory.version, factory.groupItem, quorumpb.TrxType_BLOCK_SYNCED, int64(0), encodedcontent, keyalias)
+}
+
 func (factory *TrxFactory) GetPostAnyTrx(keyalias string, content proto.Message, encryptto ...[]string) (*quorumpb.Trx, error) {
-	encodedcontent, err := quorumpb.ContentToBytes(content)
+	encodedcontent, err := factory.encodePostContent(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(encryptto) == 0 && factory.groupItem.EncryptType == quorumpb.GroupEncryptType_PRIVATE && factory.recipientResolver != nil {
+		pubkeys, err := factory.resolveRecipients()
+		if err != nil {
+			return nil, err
+		}
+		encryptto = [][]string{pubkeys}
+	}
+
+	return factory.CreateTrxByEthKey(quorumpb.TrxType_POST, encodedcontent, keyalias, encryptto...)
+}
+
+// validates, compresses and encodes the content of a POST trx
+func (factory *TrxFactory) encodePostContent(content proto.Message) ([]byte, error) {
+	if factory.schemaEngine != nil {
+		if err := factory.schemaEngine.Validate(content); err != nil {
+			return nil, err
+		}
+	}
+	if factory.activityValidator != nil {
+		if err := factory.activityValidator.Validate(content); err != nil {
+			return nil, err
+		}
+	}
+
+	compressed, err := CompressContent(content)
+	if err != nil {
+		return nil, err
+	}
+	encodedcontent, err := quorumpb.ContentToBytes(compressed)
 	if err != nil {
 		return nil, err
 	}
 
 	if binary.Size(encodedcontent) > OBJECT_SIZE_LIMIT {
-		err := errors.New("Content size over 200Kb")
+		return nil, fmt.Errorf("%w: content size over %d bytes", ErrOversize, OBJECT_SIZE_LIMIT)
+	}
+	return encodedcontent, nil
+}
+
+// GetProfileUpdateTrx publishes the sender's profile as an Update activity of a Person
+func (factory *TrxFactory) GetProfileUpdateTrx(keyalias string, person *quorumpb.Person, encryptto ...[]string) (*quorumpb.Trx, error) {
+	if err := ValidatePerson(person); err != nil {
 		return nil, err
 	}
+	return factory.GetPostAnyTrx(keyalias, ProfileActivity(person), encryptto...)
+}
 
-	return factory.CreateTrxByEthKey(quorumpb.TrxType_POST, encodedcontent, keyalias, encryptto...)
+// members resolved for the group, plus the sender itself so it can read its own posts
+func (factory *TrxFactory) resolveRecipients() ([]string, error) {
+	pubkeys, err := factory.recipientResolver.GetEncryptPubkeys(factory.groupId)
+	if err != nil {
+		return nil, err
+	}
+	self := factory.groupItem.UserEncryptPubkey
+	if self == "" {
+		return pubkeys, nil
+	}
+	for _, pubkey := range pubkeys {
+		if pubkey == self {
+			return pubkeys, nil
+		}
+	}
+	return append(pubkeys, self), nil
 }
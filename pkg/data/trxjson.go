@@ -0,0 +1,225 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	localcrypto "github.com/rumsystem/keystore/pkg/crypto"
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// signature status of a trx rendered by TrxToJSON
+const (
+	TRX_SIGNATURE_VALID      = "valid"
+	TRX_SIGNATURE_INVALID    = "invalid"
+	TRX_SIGNATURE_UNVERIFIED = "unverified"
+)
+
+// status of the data of a trx rendered by TrxToJSON
+const (
+	TRX_DATA_DECODED   = "decoded"
+	TRX_DATA_ENCRYPTED = "encrypted" //no key to decrypt it
+	TRX_DATA_ERROR     = "error"
+)
+
+var agePrefix = []byte("age-encryption.org/")
+
+// TrxJSONOptions tells TrxToJSON how to decrypt and render a trx
+type TrxJSONOptions struct {
+	CipherKeyRing   *CipherKeyRing //decrypts AES encrypted data with the key of the trx CipherKeyEpoch
+	CipherKey       string         //hex group cipher key, used when CipherKeyRing is nil
+	AgeDecrypt      bool           //decrypt age encrypted data (private group POST, direct message) with the local keystore
+	KeyAlias        string         //alias of the encrypt key, the key named by the group id if empty
+	Verify          bool           //verify the sender signature
+	ActivityStreams bool           //render POST content as ActivityStreams JSON-LD instead of protojson
+	Indent          string
+}
+
+// TrxToJSON renders a trx as JSON: the trx fields with Data replaced by the decrypted payload,
+// decoded according to the trx type. DataStatus tells whether the payload could be decoded, Data is
+// kept encoded when it could not. SignatureStatus is the result of the signature verification
+// when opts.Verify is set.
+func TrxToJSON(trx *quorumpb.Trx, opts *TrxJSONOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &TrxJSONOptions{}
+	}
+	out, err := protoToMap(&quorumpb.Trx{
+		TrxId:          trx.TrxId,
+		Type:           trx.Type,
+		GroupId:        trx.GroupId,
+		Data:           trx.Data,
+		TimeStamp:      trx.TimeStamp,
+		Version:        trx.Version,
+		Expired:        trx.Expired,
+		ResendCount:    trx.ResendCount,
+		Nonce:          trx.Nonce,
+		SenderPubkey:   trx.SenderPubkey,
+		SenderSign:     trx.SenderSign,
+		StorageType:    trx.StorageType,
+		CipherKeyEpoch: trx.CipherKeyEpoch,
+		DirectMessage:  trx.DirectMessage,
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	out["SignatureStatus"] = TRX_SIGNATURE_UNVERIFIED
+	if opts.Verify {
+		if len(trx.SenderSign) == 0 {
+			out["SignatureStatus"] = TRX_SIGNATURE_INVALID
+			out["SignatureError"] = "trx is not signed"
+		} else if ok, err := VerifyTrx(trx); ok && err == nil {
+			out["SignatureStatus"] = TRX_SIGNATURE_VALID
+		} else {
+			out["SignatureStatus"] = TRX_SIGNATURE_INVALID
+			if err != nil {
+				out["SignatureError"] = err.Error()
+			}
+		}
+	}
+
+	plain, err := decryptTrxData(trx, opts)
+	switch {
+	case err != nil:
+		out["DataStatus"] = TRX_DATA_ERROR
+		out["DataError"] = err.Error()
+	case plain == nil:
+		out["DataStatus"] = TRX_DATA_ENCRYPTED
+	default:
+		datatype, payload, err := decodeTrxPayload(trx, plain, opts)
+		if err != nil {
+			out["DataStatus"] = TRX_DATA_ERROR
+			out["DataError"] = err.Error()
+		} else {
+			out["DataStatus"] = TRX_DATA_DECODED
+			out["DataType"] = datatype
+			out["Data"] = payload
+		}
+	}
+	return json.MarshalIndent(out, "", opts.Indent)
+}
+
+// returns nil data when no key is available
+func decryptTrxData(trx *quorumpb.Trx, opts *TrxJSONOptions) ([]byte, error) {
+	if trx.DirectMessage {
+		if !opts.AgeDecrypt {
+			return nil, nil
+		}
+		return DecryptDirectMessage(trx, opts.KeyAlias)
+	}
+	if bytes.HasPrefix(trx.Data, agePrefix) {
+		if !opts.AgeDecrypt {
+			return nil, nil
+		}
+		ks := localcrypto.GetKeystore()
+		if opts.KeyAlias == "" {
+			return ks.Decrypt(trx.GroupId, trx.Data)
+		}
+		return ks.DecryptByAlias(opts.KeyAlias, trx.Data)
+	}
+	if opts.CipherKeyRing != nil {
+		return opts.CipherKeyRing.DecryptTrx(trx)
+	}
+	if opts.CipherKey != "" {
+		ring := NewCipherKeyRing(&quorumpb.GroupItem{GroupId: trx.GroupId, CipherKey: opts.CipherKey, CipherKeyEpoch: trx.CipherKeyEpoch})
+		return ring.DecryptTrx(trx)
+	}
+	return nil, nil
+}
+
+// decodes the payload of a trx by its type, returns the full name of the payload message and its JSON value
+func decodeTrxPayload(trx *quorumpb.Trx, data []byte, opts *TrxJSONOptions) (string, interface{}, error) {
+	var msg proto.Message
+	switch trx.Type {
+	case quorumpb.TrxType_POST:
+		content, _, err := DecodePostContent(trx.TrxId, data)
+		if err != nil {
+			return "", nil, err
+		}
+		typename := string(content.ProtoReflect().Descriptor().FullName())
+		if opts.ActivityStreams {
+			if doc, err := quorumpb.ToJSONLDMap(content); err == nil {
+				return typename, doc, nil
+			}
+		}
+		payload, err := protoToMap(content, false)
+		return typename, payload, err
+	case quorumpb.TrxType_BLOCK_PRODUCED:
+		block, err := GetBlockFromProducedData(data)
+		if err != nil {
+			return "", nil, err
+		}
+		msg = block
+	case quorumpb.TrxType_BLOCK_SYNCED:
+		block, err := GetBlockFromSyncedData(data)
+		if err != nil {
+			return "", nil, err
+		}
+		msg = block
+	case quorumpb.TrxType_REQ_BLOCK_RESP:
+		resp := &quorumpb.ReqBlockResp{}
+		if err := proto.Unmarshal(data, resp); err != nil {
+			return "", nil, err
+		}
+		payload, err := protoToMap(resp, true)
+		if err != nil {
+			return "", nil, err
+		}
+		//the block of the response is encoded, show it decoded
+		block := &quorumpb.Block{}
+		if err := proto.Unmarshal(resp.Block, block); err == nil && len(resp.Block) > 0 {
+			if payload["Block"], err = protoToMap(block, true); err != nil {
+				return "", nil, err
+			}
+		}
+		return string(resp.ProtoReflect().Descriptor().FullName()), payload, nil
+	case quorumpb.TrxType_SCHEMA:
+		msg = &quorumpb.SchemaItem{}
+	case quorumpb.TrxType_PRODUCER:
+		msg = &quorumpb.ProducerItem{}
+	case quorumpb.TrxType_ANNOUNCE:
+		msg = &quorumpb.AnnounceItem{}
+	case quorumpb.TrxType_USER:
+		msg = &quorumpb.UserItem{}
+	case quorumpb.TrxType_REQ_BLOCK_FORWARD, quorumpb.TrxType_REQ_BLOCK_BACKWARD:
+		msg = &quorumpb.ReqBlock{}
+	case quorumpb.TrxType_ASK_PEERID:
+		msg = &quorumpb.AskPeerId{}
+	case quorumpb.TrxType_ASK_PEERID_RESP:
+		msg = &quorumpb.AskPeerIdResp{}
+	case quorumpb.TrxType_CHAIN_CONFIG:
+		msg = &quorumpb.ChainConfigItem{}
+	case quorumpb.TrxType_APP_CONFIG:
+		msg = &quorumpb.AppConfigItem{}
+	case quorumpb.TrxType_CIPHER_KEY:
+		msg = &quorumpb.CipherKeyItem{}
+	case quorumpb.TrxType_STAKE:
+		msg = &quorumpb.StakeItem{}
+	default:
+		return "", nil, fmt.Errorf("unknown trx type %s", trx.Type)
+	}
+	if _, isblock := msg.(*quorumpb.Block); !isblock {
+		if err := proto.Unmarshal(data, msg); err != nil {
+			return "", nil, err
+		}
+	}
+	payload, err := protoToMap(msg, true)
+	return string(msg.ProtoReflect().Descriptor().FullName()), payload, err
+}
+
+func protoToMap(msg proto.Message, emitUnpopulated bool) (map[string]interface{}, error) {
+	encoded, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: emitUnpopulated}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,75 @@
+package data
+
+import (
+	"encoding/json"
+	"testing"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+)
+
+func TestTrxToJSON(t *testing.T) {
+	keystoreDir := t.TempDir()
+	tn := &TestNonce{}
+	trxFactory := &TrxFactory{}
+	groupitem := GetGroupItem()
+	trxFactory.Init("1.0.0", groupitem, "default", tn)
+	_, pubkey, err := GetKeyStorePubKey(groupitem.GroupId, keystoreDir)
+	if err != nil {
+		t.Fatalf("keystore new key err : %s", err)
+	}
+	groupitem.UserSignPubkey = pubkey
+
+	render := func(trx *quorumpb.Trx, opts *TrxJSONOptions) map[string]interface{} {
+		out, err := TrxToJSON(trx, opts)
+		if err != nil {
+			t.Fatalf("render trx err: %s", err)
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(out, &doc); err != nil {
+			t.Fatalf("invalid json %s: %s", out, err)
+		}
+		return doc
+	}
+
+	obj := &quorumpb.Object{Type: "Note", Content: "test content"}
+	trx, err := trxFactory.GetPostAnyTrx("", &quorumpb.Activity{Type: "Add", Object: obj, Target: &quorumpb.Object{Id: groupitem.GroupId, Type: "Group"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := render(trx, &TrxJSONOptions{CipherKey: groupitem.CipherKey, Verify: true, ActivityStreams: true})
+	if doc["Type"] != "POST" || doc["SignatureStatus"] != TRX_SIGNATURE_VALID || doc["DataStatus"] != TRX_DATA_DECODED || doc["DataType"] != "quorum.pb.Activity" {
+		t.Errorf("unexpected rendered post: %v", doc)
+	}
+	activity, _ := doc["Data"].(map[string]interface{})
+	if activity["type"] != "Add" || activity["@context"] == nil {
+		t.Errorf("post content not rendered as activity streams: %v", doc["Data"])
+	}
+
+	//without key the data stays encrypted
+	doc = render(trx, nil)
+	if doc["DataStatus"] != TRX_DATA_ENCRYPTED || doc["SignatureStatus"] != TRX_SIGNATURE_UNVERIFIED || doc["Data"] == nil {
+		t.Errorf("unexpected rendered encrypted post: %v", doc)
+	}
+
+	trx.TrxId = "modified"
+	if doc = render(trx, &TrxJSONOptions{Verify: true}); doc["SignatureStatus"] != TRX_SIGNATURE_INVALID {
+		t.Errorf("modified trx rendered with signature %v", doc["SignatureStatus"])
+	}
+
+	configtrx, err := trxFactory.GetUpdAppConfigTrx("", &quorumpb.AppConfigItem{GroupId: groupitem.GroupId, Name: "name", Type: quorumpb.AppConfigType_STRING, Value: "value"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc = render(configtrx, &TrxJSONOptions{CipherKey: groupitem.CipherKey})
+	item, _ := doc["Data"].(map[string]interface{})
+	if doc["DataType"] != "quorum.pb.AppConfigItem" || item["Name"] != "name" || item["Type"] != "STRING" {
+		t.Errorf("unexpected rendered app config: %v", doc)
+	}
+
+	//a wrong key is reported
+	doc = render(configtrx, &TrxJSONOptions{CipherKey: "00" + groupitem.CipherKey[2:]})
+	if doc["DataStatus"] != TRX_DATA_ERROR || doc["DataError"] == nil {
+		t.Errorf("decrypt with a wrong key not reported: %v", doc)
+	}
+}
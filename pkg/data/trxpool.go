@@ -0,0 +1,272 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	TRXPOOL_LIMIT        = 10000 //pending trxs in a pool
+	TRXPOOL_SENDER_LIMIT = 100   //pending trxs of a sender
+
+	TRXPOOL_MAX_TTL   = 10 * time.Minute //time a trx stays in a pool after its TimeStamp or its arrival, whatever its Expired
+	TRXPOOL_MAX_DRIFT = 5 * time.Second  //accepted advance of the TimeStamp of a trx on the clock of the pool
+)
+
+// errors of TrxPool.Add, besides the verification errors
+var (
+	ErrDuplicateTrx = errors.New("duplicate trx")
+	ErrTrxPoolFull  = errors.New("trx pool full")
+	ErrFutureTrx    = errors.New("trx timestamp in the future")
+)
+
+// TrxPoolOptions overrides the limits and the clock of a TrxPool
+type TrxPoolOptions struct {
+	Limit       int              //TRXPOOL_LIMIT if 0
+	SenderLimit int              //TRXPOOL_SENDER_LIMIT if 0
+	MaxTTL      time.Duration    //TRXPOOL_MAX_TTL if 0
+	MaxDrift    time.Duration    //TRXPOOL_MAX_DRIFT if 0
+	Now         func() time.Time //time.Now if nil
+}
+
+// TrxPool holds the verified trxs of a group waiting for the next block of a producer.
+// A trx is unique by TrxId and by (SenderPubkey, Nonce), expired trxs are evicted. It is safe for concurrent use.
+// Nonce 0 is the nonce of the trxs created without one (REQ_BLOCK_*, BLOCK_*), it is not unique per sender.
+// A trx expires at its Expired or MaxTTL after its TimeStamp or its arrival, whichever comes first, so a
+// trx without Expired or with a far one does not stay forever. Trxs with a TimeStamp after MaxDrift are rejected.
+type TrxPool struct {
+	groupId     string
+	limit       int
+	senderLimit int
+	maxTTL      time.Duration
+	maxDrift    time.Duration
+	now         func() time.Time
+
+	mu      sync.RWMutex
+	trxs    map[string]*pooledTrx
+	nonces  map[senderNonce]string
+	senders map[string]int
+}
+
+type pooledTrx struct {
+	trx      *quorumpb.Trx
+	size     int
+	inserted time.Time
+}
+
+type senderNonce struct {
+	Sender string
+	Nonce  int64
+}
+
+func NewTrxPool(groupId string, opts *TrxPoolOptions) *TrxPool {
+	pool := &TrxPool{
+		groupId:     groupId,
+		limit:       TRXPOOL_LIMIT,
+		senderLimit: TRXPOOL_SENDER_LIMIT,
+		maxTTL:      TRXPOOL_MAX_TTL,
+		maxDrift:    TRXPOOL_MAX_DRIFT,
+		now:         time.Now,
+		trxs:        make(map[string]*pooledTrx),
+		nonces:      make(map[senderNonce]string),
+		senders:     make(map[string]int),
+	}
+	if opts != nil {
+		if opts.Limit > 0 {
+			pool.limit = opts.Limit
+		}
+		if opts.SenderLimit > 0 {
+			pool.senderLimit = opts.SenderLimit
+		}
+		if opts.MaxTTL > 0 {
+			pool.maxTTL = opts.MaxTTL
+		}
+		if opts.MaxDrift > 0 {
+			pool.maxDrift = opts.MaxDrift
+		}
+		if opts.Now != nil {
+			pool.now = opts.Now
+		}
+	}
+	return pool
+}
+
+// Add verifies the trx and adds it to the pool. It fails with ErrExpired, ErrFutureTrx and the errors of VerifyTrx
+// for an invalid trx, ErrDuplicateTrx for a trx already pooled and ErrTrxPoolFull over the limits.
+func (pool *TrxPool) Add(trx *quorumpb.Trx) error {
+	if trx.GroupId != pool.groupId {
+		return fmt.Errorf("trx %s for group %s, expect group %s", trx.TrxId, trx.GroupId, pool.groupId)
+	}
+	now := pool.now()
+	if timestamp := time.Unix(0, trx.TimeStamp); timestamp.After(now.Add(pool.maxDrift)) {
+		return trxVerifyError(trx.TrxId, fmt.Errorf("%w: %s, %s ahead of %s", ErrFutureTrx, timestamp.UTC().Format(time.RFC3339), pool.maxDrift, now.UTC().Format(time.RFC3339)))
+	}
+	if err := pool.verifyExpiry(trx, now, now); err != nil {
+		return err
+	}
+	if pool.Contains(trx.TrxId) {
+		return fmt.Errorf("%w: trx %s", ErrDuplicateTrx, trx.TrxId)
+	}
+	//verify out of the lock, the pool is checked again after
+	if _, err := VerifyTrx(trx); err != nil {
+		return err
+	}
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if _, ok := pool.trxs[trx.TrxId]; ok {
+		return fmt.Errorf("%w: trx %s", ErrDuplicateTrx, trx.TrxId)
+	}
+	key := senderNonce{Sender: trx.SenderPubkey, Nonce: trx.Nonce}
+	if trxid, ok := pool.nonces[key]; ok {
+		return fmt.Errorf("%w: nonce %d of %s already used by trx %s", ErrDuplicateTrx, trx.Nonce, trx.SenderPubkey, trxid)
+	}
+	if pool.senders[trx.SenderPubkey] >= pool.senderLimit {
+		return fmt.Errorf("%w: %d trxs of %s", ErrTrxPoolFull, pool.senderLimit, trx.SenderPubkey)
+	}
+	if len(pool.trxs) >= pool.limit {
+		return fmt.Errorf("%w: %d trxs", ErrTrxPoolFull, pool.limit)
+	}
+	pool.trxs[trx.TrxId] = &pooledTrx{trx: proto.Clone(trx).(*quorumpb.Trx), size: proto.Size(trx), inserted: now}
+	if trx.Nonce != 0 {
+		pool.nonces[key] = trx.TrxId
+	}
+	pool.senders[trx.SenderPubkey]++
+	return nil
+}
+
+// Contains reports whether the trx is in the pool
+func (pool *TrxPool) Contains(trxId string) bool {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	_, ok := pool.trxs[trxId]
+	return ok
+}
+
+// Len returns the number of trxs in the pool
+func (pool *TrxPool) Len() int {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	return len(pool.trxs)
+}
+
+// Remove removes trxs from the pool, once they are in a block
+func (pool *TrxPool) Remove(trxIds ...string) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	for _, trxId := range trxIds {
+		pool.remove(trxId)
+	}
+}
+
+// RemoveBlock removes the trxs of a block from the pool
+func (pool *TrxPool) RemoveBlock(block *quorumpb.Block) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	for _, trx := range block.Trxs {
+		pool.remove(trx.TrxId)
+	}
+}
+
+// Evict removes the expired trxs and returns how many were removed
+func (pool *TrxPool) Evict() int {
+	now := pool.now()
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	evicted := 0
+	for trxId, pooled := range pool.trxs {
+		if pool.verifyExpiry(pooled.trx, pooled.inserted, now) != nil {
+			pool.remove(trxId)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// Batch returns the trxs for the next block, at most maxBytes of encoded trxs, without removing them.
+// Expired trxs are evicted first. The order only depends on the trxs in the pool: the trxs of a sender
+// follow each other by nonce, and the senders are merged by the TimeStamp of their next trx, then by
+// sender pubkey. A trx which does not fit ends the batch of its sender, the other senders can still fill it.
+func (pool *TrxPool) Batch(maxBytes int) []*quorumpb.Trx {
+	pool.Evict()
+
+	pool.mu.RLock()
+	queues := make(map[string][]*pooledTrx)
+	for _, pooled := range pool.trxs {
+		queues[pooled.trx.SenderPubkey] = append(queues[pooled.trx.SenderPubkey], pooled)
+	}
+	pool.mu.RUnlock()
+
+	senders := make([]string, 0, len(queues))
+	for sender, queue := range queues {
+		sort.Slice(queue, func(i, j int) bool {
+			if queue[i].trx.Nonce != queue[j].trx.Nonce {
+				return queue[i].trx.Nonce < queue[j].trx.Nonce
+			}
+			return queue[i].trx.TrxId < queue[j].trx.TrxId
+		})
+		senders = append(senders, sender)
+	}
+	sort.Strings(senders)
+
+	var batch []*quorumpb.Trx
+	size := 0
+	for len(senders) > 0 {
+		//the sender with the oldest next trx
+		next := 0
+		for i, sender := range senders[1:] {
+			if queues[sender][0].trx.TimeStamp < queues[senders[next]][0].trx.TimeStamp {
+				next = i + 1
+			}
+		}
+		sender := senders[next]
+		pooled := queues[sender][0]
+		if size+pooled.size > maxBytes {
+			senders = append(senders[:next], senders[next+1:]...)
+			continue
+		}
+		batch = append(batch, proto.Clone(pooled.trx).(*quorumpb.Trx))
+		size += pooled.size
+		if queues[sender] = queues[sender][1:]; len(queues[sender]) == 0 {
+			senders = append(senders[:next], senders[next+1:]...)
+		}
+	}
+	return batch
+}
+
+// verifyExpiry fails with ErrExpired after the Expired of the trx or after maxTTL from its TimeStamp or
+// from inserted, whichever is first
+func (pool *TrxPool) verifyExpiry(trx *quorumpb.Trx, inserted time.Time, now time.Time) error {
+	if err := VerifyTrxExpiry(trx, now); err != nil {
+		return err
+	}
+	start := time.Unix(0, trx.TimeStamp)
+	if inserted.Before(start) {
+		start = inserted
+	}
+	if deadline := start.Add(pool.maxTTL); now.After(deadline) {
+		return trxVerifyError(trx.TrxId, fmt.Errorf("%w at %s, %s after its timestamp or arrival", ErrExpired, deadline.UTC().Format(time.RFC3339), pool.maxTTL))
+	}
+	return nil
+}
+
+// must be called with pool.mu locked
+func (pool *TrxPool) remove(trxId string) {
+	pooled, ok := pool.trxs[trxId]
+	if !ok {
+		return
+	}
+	delete(pool.trxs, trxId)
+	if pooled.trx.Nonce != 0 {
+		delete(pool.nonces, senderNonce{Sender: pooled.trx.SenderPubkey, Nonce: pooled.trx.Nonce})
+	}
+	if pool.senders[pooled.trx.SenderPubkey]--; pool.senders[pooled.trx.SenderPubkey] == 0 {
+		delete(pool.senders, pooled.trx.SenderPubkey)
+	}
+}
@@ -0,0 +1,251 @@
+package data
+
+import (
+	"crypto/ecdsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	quorumpb "github.com/rumsystem/rumchaindata/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+var trxPoolTestTime = time.Unix(1700000000, 0)
+
+func newPoolTestTrx(t testing.TB, key *ecdsa.PrivateKey, id string, nonce int64, offset time.Duration) *quorumpb.Trx {
+	timestamp := trxPoolTestTime.Add(offset)
+	trx := &quorumpb.Trx{
+		TrxId:        id,
+		Type:         quorumpb.TrxType_POST,
+		GroupId:      GetGroupItem().GroupId,
+		Data:         []byte("data of " + id),
+		TimeStamp:    timestamp.UnixNano(),
+		Expired:      timestamp.Add(30 * time.Second).UnixNano(),
+		Version:      "1.0.0",
+		Nonce:        nonce,
+		SenderPubkey: base64.RawURLEncoding.EncodeToString(ethcrypto.CompressPubkey(&key.PublicKey)),
+	}
+	hash, err := TrxHash(trx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trx.SenderSign, err = ethcrypto.Sign(hash, key); err != nil {
+		t.Fatal(err)
+	}
+	return trx
+}
+
+func trxIds(trxs []*quorumpb.Trx) []string {
+	var ids []string
+	for _, trx := range trxs {
+		ids = append(ids, trx.TrxId)
+	}
+	return ids
+}
+
+func TestTrxPool(t *testing.T) {
+	groupId := GetGroupItem().GroupId
+	if _, _, err := GetKeyStorePubKey(groupId, t.TempDir()); err != nil {
+		t.Fatalf("keystore new key err : %s", err)
+	}
+	now := trxPoolTestTime
+	var clock sync.Mutex
+	pool := NewTrxPool(groupId, &TrxPoolOptions{SenderLimit: 3, Now: func() time.Time {
+		clock.Lock()
+		defer clock.Unlock()
+		return now
+	}})
+
+	keya, _ := ethcrypto.GenerateKey()
+	keyb, _ := ethcrypto.GenerateKey()
+	a1 := newPoolTestTrx(t, keya, "a1", 1, time.Second)
+	a2 := newPoolTestTrx(t, keya, "a2", 2, 3*time.Second)
+	a3 := newPoolTestTrx(t, keya, "a3", 3, 0) //before a2 by time, after it by nonce
+	b1 := newPoolTestTrx(t, keyb, "b1", 1, 2*time.Second)
+	for _, trx := range []*quorumpb.Trx{a2, b1, a3, a1} {
+		if err := pool.Add(trx); err != nil {
+			t.Fatalf("add trx %s err: %s", trx.TrxId, err)
+		}
+	}
+
+	tampered := newPoolTestTrx(t, keyb, "b2", 2, 0)
+	tampered.Data = []byte("tampered")
+	wronggroup := newPoolTestTrx(t, keyb, "b3", 3, 0)
+	wronggroup.GroupId = "another group"
+	for _, c := range []struct {
+		trx    *quorumpb.Trx
+		expect error
+	}{
+		{a1, ErrDuplicateTrx},
+		{newPoolTestTrx(t, keya, "a1bis", 1, 0), ErrDuplicateTrx},
+		{newPoolTestTrx(t, keya, "a4", 4, 0), ErrTrxPoolFull},
+		{newPoolTestTrx(t, keyb, "b0", 0, -time.Minute), ErrExpired},
+		{tampered, ErrBadSignature},
+		{wronggroup, nil},
+	} {
+		err := pool.Add(c.trx)
+		if err == nil || (c.expect != nil && !errors.Is(err, c.expect)) {
+			t.Errorf("add trx %s: got %v, expect %v", c.trx.TrxId, err, c.expect)
+		}
+	}
+	if pool.Len() != 4 {
+		t.Fatalf("pool has %d trxs, expect 4", pool.Len())
+	}
+
+	expect := []string{"a1", "b1", "a2", "a3"}
+	if got := trxIds(pool.Batch(1 << 20)); fmt.Sprint(got) != fmt.Sprint(expect) {
+		t.Errorf("batch %v, expect %v", got, expect)
+	}
+	//a2 does not fit, the batch of sender a ends
+	if got := trxIds(pool.Batch(proto.Size(a1) + proto.Size(b1) + 1)); fmt.Sprint(got) != "[a1 b1]" {
+		t.Errorf("bounded batch %v", got)
+	}
+
+	//the batch is the same whatever the order of arrival
+	other := NewTrxPool(groupId, &TrxPoolOptions{Now: func() time.Time { return trxPoolTestTime }})
+	for _, trx := range []*quorumpb.Trx{a1, a3, b1, a2} {
+		if err := other.Add(trx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := trxIds(other.Batch(1 << 20)); fmt.Sprint(got) != fmt.Sprint(expect) {
+		t.Errorf("batch of another pool %v, expect %v", got, expect)
+	}
+
+	pool.RemoveBlock(&quorumpb.Block{Trxs: []*quorumpb.Trx{b1}})
+	if pool.Contains("b1") || pool.Len() != 3 {
+		t.Errorf("trx of the block not removed")
+	}
+
+	//a1 and a3 expire first, their nonces can be used again
+	clock.Lock()
+	now = trxPoolTestTime.Add(32 * time.Second)
+	clock.Unlock()
+	if evicted := pool.Evict(); evicted != 2 || pool.Len() != 1 {
+		t.Errorf("evicted %d trxs, %d left", evicted, pool.Len())
+	}
+	if err := pool.Add(newPoolTestTrx(t, keya, "a1bis", 1, 31*time.Second)); err != nil {
+		t.Errorf("nonce of an evicted trx rejected: %s", err)
+	}
+}
+
+func TestTrxPoolTTL(t *testing.T) {
+	groupId := GetGroupItem().GroupId
+	if _, _, err := GetKeyStorePubKey(groupId, t.TempDir()); err != nil {
+		t.Fatalf("keystore new key err : %s", err)
+	}
+	now := trxPoolTestTime
+	pool := NewTrxPool(groupId, &TrxPoolOptions{MaxTTL: time.Minute, Now: func() time.Time { return now }})
+
+	key, _ := ethcrypto.GenerateKey()
+	resign := func(trx *quorumpb.Trx) *quorumpb.Trx {
+		hash, err := TrxHash(trx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trx.SenderSign, err = ethcrypto.Sign(hash, key); err != nil {
+			t.Fatal(err)
+		}
+		return trx
+	}
+	forever := newPoolTestTrx(t, key, "forever", 1, 0)
+	forever.Expired = 0
+	far := newPoolTestTrx(t, key, "far", 2, 0)
+	far.Expired = trxPoolTestTime.Add(24 * time.Hour).UnixNano()
+	//trxs without a nonce do not collide
+	req1 := newPoolTestTrx(t, key, "req1", 0, 0)
+	req2 := newPoolTestTrx(t, key, "req2", 0, 0)
+	for _, trx := range []*quorumpb.Trx{resign(forever), resign(far), req1, req2} {
+		if err := pool.Add(trx); err != nil {
+			t.Fatalf("add trx %s err: %s", trx.TrxId, err)
+		}
+	}
+	pool.Remove("req1")
+	if !pool.Contains("req2") {
+		t.Errorf("trx with nonce 0 removed with another one")
+	}
+
+	now = trxPoolTestTime.Add(time.Minute + time.Second)
+	if evicted := pool.Evict(); evicted != 3 || pool.Len() != 0 {
+		t.Errorf("evicted %d trxs, %d left", evicted, pool.Len())
+	}
+	old := newPoolTestTrx(t, key, "old", 3, -2*time.Minute)
+	old.Expired = 0
+	if err := pool.Add(resign(old)); !errors.Is(err, ErrExpired) {
+		t.Errorf("add trx older than the ttl: got %v, expect %v", err, ErrExpired)
+	}
+
+	//a trx from the future is rejected, one within the drift expires from its arrival
+	future := newPoolTestTrx(t, key, "future", 4, 24*365*time.Hour)
+	future.Expired = 0
+	if err := pool.Add(resign(future)); !errors.Is(err, ErrFutureTrx) {
+		t.Errorf("add trx from the future: got %v, expect %v", err, ErrFutureTrx)
+	}
+	ahead := newPoolTestTrx(t, key, "ahead", 5, time.Minute+5*time.Second)
+	ahead.Expired = 0
+	if err := pool.Add(resign(ahead)); err != nil {
+		t.Fatalf("add trx within the drift err: %s", err)
+	}
+	now = now.Add(time.Minute + time.Second)
+	if evicted := pool.Evict(); evicted != 1 || pool.Len() != 0 {
+		t.Errorf("trx ahead not evicted from its arrival: evicted %d trxs, %d left", evicted, pool.Len())
+	}
+}
+
+func TestTrxPoolConcurrent(t *testing.T) {
+	groupId := GetGroupItem().GroupId
+	if _, _, err := GetKeyStorePubKey(groupId, t.TempDir()); err != nil {
+		t.Fatalf("keystore new key err : %s", err)
+	}
+	const senders, count = 8, 20
+	var trxs [][]*quorumpb.Trx
+	for s := 0; s < senders; s++ {
+		key, _ := ethcrypto.GenerateKey()
+		var sendertrxs []*quorumpb.Trx
+		for n := 0; n < count; n++ {
+			sendertrxs = append(sendertrxs, newPoolTestTrx(t, key, fmt.Sprintf("%d-%d", s, n), int64(n), time.Duration(n)*time.Millisecond))
+		}
+		trxs = append(trxs, sendertrxs)
+	}
+
+	pool := NewTrxPool(groupId, &TrxPoolOptions{Now: func() time.Time { return trxPoolTestTime }})
+	var wg sync.WaitGroup
+	for s := 0; s < senders; s++ {
+		wg.Add(2)
+		go func(sendertrxs []*quorumpb.Trx) {
+			defer wg.Done()
+			for _, trx := range sendertrxs {
+				if err := pool.Add(trx); err != nil {
+					t.Errorf("add trx %s err: %s", trx.TrxId, err)
+				}
+				pool.Add(trx) //duplicate
+			}
+		}(trxs[s])
+		go func() {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				nonces := make(map[string]int64)
+				for _, trx := range pool.Batch(4096) {
+					if last, ok := nonces[trx.SenderPubkey]; ok && trx.Nonce <= last {
+						t.Errorf("trx %s after nonce %d in the batch", trx.TrxId, last)
+					}
+					nonces[trx.SenderPubkey] = trx.Nonce
+				}
+				pool.Evict()
+				pool.Remove("unknown")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if pool.Len() != senders*count {
+		t.Errorf("pool has %d trxs, expect %d", pool.Len(), senders*count)
+	}
+	if batch := pool.Batch(1 << 20); len(batch) != senders*count {
+		t.Errorf("batch has %d trxs, expect %d", len(batch), senders*count)
+	}
+}
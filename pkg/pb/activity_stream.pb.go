@@ -555,11 +555,13 @@ type Image struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Id        string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Name      string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	MediaType string `protobuf:"bytes,3,opt,name=mediaType,proto3" json:"mediaType,omitempty"`
-	Content   []byte `protobuf:"bytes,4,opt,name=content,proto3" json:"content,omitempty"`
-	Url       string `protobuf:"bytes,5,opt,name=url,proto3" json:"url,omitempty"`
+	Id          string           `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name        string           `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	MediaType   string           `protobuf:"bytes,3,opt,name=mediaType,proto3" json:"mediaType,omitempty"`
+	Content     []byte           `protobuf:"bytes,4,opt,name=content,proto3" json:"content,omitempty"`
+	Url         string           `protobuf:"bytes,5,opt,name=url,proto3" json:"url,omitempty"`
+	Compression File_Compression `protobuf:"varint,6,opt,name=compression,proto3,enum=quorum.pb.File_Compression" json:"compression,omitempty"`
+	Compressed  bool             `protobuf:"varint,7,opt,name=compressed,proto3" json:"compressed,omitempty"`
 }
 
 func (x *Image) Reset() {
@@ -629,6 +631,20 @@ func (x *Image) GetUrl() string {
 	return ""
 }
 
+func (x *Image) GetCompression() File_Compression {
+	if x != nil {
+		return x.Compression
+	}
+	return File_none
+}
+
+func (x *Image) GetCompressed() bool {
+	if x != nil {
+		return x.Compressed
+	}
+	return false
+}
+
 type File struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -640,6 +656,7 @@ type File struct {
 	Compression File_Compression `protobuf:"varint,4,opt,name=compression,proto3,enum=quorum.pb.File_Compression" json:"compression,omitempty"`
 	Content     []byte           `protobuf:"bytes,5,opt,name=content,proto3" json:"content,omitempty"`
 	Url         string           `protobuf:"bytes,6,opt,name=url,proto3" json:"url,omitempty"`
+	Compressed  bool             `protobuf:"varint,7,opt,name=compressed,proto3" json:"compressed,omitempty"`
 }
 
 func (x *File) Reset() {
@@ -716,6 +733,13 @@ func (x *File) GetUrl() string {
 	return ""
 }
 
+func (x *File) GetCompressed() bool {
+	if x != nil {
+		return x.Compressed
+	}
+	return false
+}
+
 type Person struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -1169,6 +1193,235 @@ func (x *Activity) GetPerson() *Person {
 	return nil
 }
 
+type FileChunk struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Fileid  string `protobuf:"bytes,1,opt,name=fileid,proto3" json:"fileid,omitempty"`
+	Index   int32  `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
+	Content []byte `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+}
+
+func (x *FileChunk) Reset() {
+	*x = FileChunk{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_activity_stream_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FileChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileChunk) ProtoMessage() {}
+
+func (x *FileChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_activity_stream_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileChunk.ProtoReflect.Descriptor instead.
+func (*FileChunk) Descriptor() ([]byte, []int) {
+	return file_activity_stream_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *FileChunk) GetFileid() string {
+	if x != nil {
+		return x.Fileid
+	}
+	return ""
+}
+
+func (x *FileChunk) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *FileChunk) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+type FileChunkInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Index int32  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Size  int64  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Hash  []byte `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`
+}
+
+func (x *FileChunkInfo) Reset() {
+	*x = FileChunkInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_activity_stream_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FileChunkInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileChunkInfo) ProtoMessage() {}
+
+func (x *FileChunkInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_activity_stream_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileChunkInfo.ProtoReflect.Descriptor instead.
+func (*FileChunkInfo) Descriptor() ([]byte, []int) {
+	return file_activity_stream_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *FileChunkInfo) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *FileChunkInfo) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FileChunkInfo) GetHash() []byte {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+type FileManifest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id          string           `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name        string           `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	MediaType   string           `protobuf:"bytes,3,opt,name=mediaType,proto3" json:"mediaType,omitempty"`
+	Compression File_Compression `protobuf:"varint,4,opt,name=compression,proto3,enum=quorum.pb.File_Compression" json:"compression,omitempty"`
+	Size        int64            `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`
+	Hash        []byte           `protobuf:"bytes,6,opt,name=hash,proto3" json:"hash,omitempty"`
+	Chunks      []*FileChunkInfo `protobuf:"bytes,7,rep,name=chunks,proto3" json:"chunks,omitempty"`
+	Url         string           `protobuf:"bytes,8,opt,name=url,proto3" json:"url,omitempty"`
+}
+
+func (x *FileManifest) Reset() {
+	*x = FileManifest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_activity_stream_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FileManifest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileManifest) ProtoMessage() {}
+
+func (x *FileManifest) ProtoReflect() protoreflect.Message {
+	mi := &file_activity_stream_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileManifest.ProtoReflect.Descriptor instead.
+func (*FileManifest) Descriptor() ([]byte, []int) {
+	return file_activity_stream_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *FileManifest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *FileManifest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *FileManifest) GetMediaType() string {
+	if x != nil {
+		return x.MediaType
+	}
+	return ""
+}
+
+func (x *FileManifest) GetCompression() File_Compression {
+	if x != nil {
+		return x.Compression
+	}
+	return File_none
+}
+
+func (x *FileManifest) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FileManifest) GetHash() []byte {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+func (x *FileManifest) GetChunks() []*FileChunkInfo {
+	if x != nil {
+		return x.Chunks
+	}
+	return nil
+}
+
+func (x *FileManifest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
 var File_activity_stream_proto protoreflect.FileDescriptor
 
 var file_activity_stream_proto_rawDesc = []byte{
@@ -1271,14 +1524,20 @@ var file_activity_stream_proto_rawDesc = []byte{
 	0x12, 0x14, 0x0a, 0x05, 0x74, 0x72, 0x78, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
 	0x05, 0x74, 0x72, 0x78, 0x69, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x69,
 	0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x69, 0x64,
-	0x22, 0x75, 0x0a, 0x05, 0x49, 0x6d, 0x61, 0x67, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d,
-	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1c, 0x0a,
-	0x09, 0x6d, 0x65, 0x64, 0x69, 0x61, 0x54, 0x79, 0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x09, 0x6d, 0x65, 0x64, 0x69, 0x61, 0x54, 0x79, 0x70, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x63,
-	0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x63, 0x6f,
-	0x6e, 0x74, 0x65, 0x6e, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18, 0x05, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x22, 0xe7, 0x01, 0x0a, 0x04, 0x46, 0x69, 0x6c, 0x65,
+	0x22, 0xd4, 0x01, 0x0a, 0x05, 0x49, 0x6d, 0x61, 0x67, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1c,
+	0x0a, 0x09, 0x6d, 0x65, 0x64, 0x69, 0x61, 0x54, 0x79, 0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x09, 0x6d, 0x65, 0x64, 0x69, 0x61, 0x54, 0x79, 0x70, 0x65, 0x12, 0x18, 0x0a, 0x07,
+	0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x63,
+	0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x12, 0x3d, 0x0a, 0x0b, 0x63, 0x6f, 0x6d, 0x70,
+	0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1b, 0x2e,
+	0x71, 0x75, 0x6f, 0x72, 0x75, 0x6d, 0x2e, 0x70, 0x62, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x2e, 0x43,
+	0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x0b, 0x63, 0x6f, 0x6d, 0x70,
+	0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x6f, 0x6d, 0x70, 0x72,
+	0x65, 0x73, 0x73, 0x65, 0x64, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x63, 0x6f, 0x6d,
+	0x70, 0x72, 0x65, 0x73, 0x73, 0x65, 0x64, 0x22, 0x87, 0x02, 0x0a, 0x04, 0x46, 0x69, 0x6c, 0x65,
 	0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64,
 	0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
 	0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x6d, 0x65, 0x64, 0x69, 0x61, 0x54, 0x79, 0x70,
@@ -1289,7 +1548,9 @@ var file_activity_stream_proto_rawDesc = []byte{
 	0x73, 0x69, 0x6f, 0x6e, 0x52, 0x0b, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f,
 	0x6e, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18, 0x05, 0x20, 0x01,
 	0x28, 0x0c, 0x52, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x75,
-	0x72, 0x6c, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x22, 0x32, 0x0a,
+	0x72, 0x6c, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x12, 0x1e, 0x0a,
+	0x0a, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x65, 0x64, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x0a, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x65, 0x64, 0x22, 0x32, 0x0a,
 	0x0b, 0x43, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x08, 0x0a, 0x04,
 	0x6e, 0x6f, 0x6e, 0x65, 0x10, 0x00, 0x12, 0x06, 0x0a, 0x02, 0x67, 0x7a, 0x10, 0x01, 0x12, 0x07,
 	0x0a, 0x03, 0x7a, 0x69, 0x70, 0x10, 0x02, 0x12, 0x08, 0x0a, 0x04, 0x7a, 0x73, 0x74, 0x64, 0x10,
@@ -1395,10 +1656,36 @@ var file_activity_stream_proto_rawDesc = []byte{
 	0x74, 0x52, 0x0a, 0x69, 0x6e, 0x73, 0x74, 0x72, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x29, 0x0a,
 	0x06, 0x70, 0x65, 0x72, 0x73, 0x6f, 0x6e, 0x18, 0x23, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e,
 	0x71, 0x75, 0x6f, 0x72, 0x75, 0x6d, 0x2e, 0x70, 0x62, 0x2e, 0x50, 0x65, 0x72, 0x73, 0x6f, 0x6e,
-	0x52, 0x06, 0x70, 0x65, 0x72, 0x73, 0x6f, 0x6e, 0x42, 0x2a, 0x5a, 0x28, 0x67, 0x69, 0x74, 0x68,
-	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x72, 0x75, 0x6d, 0x73, 0x79, 0x73, 0x74, 0x65, 0x6d,
-	0x2f, 0x72, 0x75, 0x6d, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x64, 0x61, 0x74, 0x61, 0x2f, 0x70, 0x6b,
-	0x67, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x52, 0x06, 0x70, 0x65, 0x72, 0x73, 0x6f, 0x6e, 0x22, 0x53, 0x0a, 0x09, 0x46, 0x69, 0x6c, 0x65,
+	0x43, 0x68, 0x75, 0x6e, 0x6b, 0x12, 0x16, 0x0a, 0x06, 0x66, 0x69, 0x6c, 0x65, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x66, 0x69, 0x6c, 0x65, 0x69, 0x64, 0x12, 0x14, 0x0a,
+	0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x69, 0x6e,
+	0x64, 0x65, 0x78, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x22, 0x4d, 0x0a,
+	0x0d, 0x46, 0x69, 0x6c, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x14,
+	0x0a, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x69,
+	0x6e, 0x64, 0x65, 0x78, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x22, 0xfb, 0x01, 0x0a,
+	0x0c, 0x46, 0x69, 0x6c, 0x65, 0x4d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a,
+	0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d,
+	0x65, 0x12, 0x1c, 0x0a, 0x09, 0x6d, 0x65, 0x64, 0x69, 0x61, 0x54, 0x79, 0x70, 0x65, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x6d, 0x65, 0x64, 0x69, 0x61, 0x54, 0x79, 0x70, 0x65, 0x12,
+	0x3d, 0x0a, 0x0b, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x1b, 0x2e, 0x71, 0x75, 0x6f, 0x72, 0x75, 0x6d, 0x2e, 0x70, 0x62,
+	0x2e, 0x46, 0x69, 0x6c, 0x65, 0x2e, 0x43, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f,
+	0x6e, 0x52, 0x0b, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x12,
+	0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x69,
+	0x7a, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x12, 0x30, 0x0a, 0x06, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x73,
+	0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x71, 0x75, 0x6f, 0x72, 0x75, 0x6d, 0x2e,
+	0x70, 0x62, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x49, 0x6e, 0x66, 0x6f,
+	0x52, 0x06, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x73, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18,
+	0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x42, 0x2a, 0x5a, 0x28, 0x67, 0x69,
+	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x72, 0x75, 0x6d, 0x73, 0x79, 0x73, 0x74,
+	0x65, 0x6d, 0x2f, 0x72, 0x75, 0x6d, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x64, 0x61, 0x74, 0x61, 0x2f,
+	0x70, 0x6b, 0x67, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -1414,7 +1701,7 @@ func file_activity_stream_proto_rawDescGZIP() []byte {
 }
 
 var file_activity_stream_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_activity_stream_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_activity_stream_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
 var file_activity_stream_proto_goTypes = []interface{}{
 	(File_Compression)(0),         // 0: quorum.pb.File.Compression
 	(*AnyObj)(nil),                // 1: quorum.pb.AnyObj
@@ -1426,27 +1713,30 @@ var file_activity_stream_proto_goTypes = []interface{}{
 	(*Person)(nil),                // 7: quorum.pb.Person
 	(*Payment)(nil),               // 8: quorum.pb.Payment
 	(*Activity)(nil),              // 9: quorum.pb.Activity
-	(*anypb.Any)(nil),             // 10: google.protobuf.Any
-	(*timestamppb.Timestamp)(nil), // 11: google.protobuf.Timestamp
+	(*FileChunk)(nil),             // 10: quorum.pb.FileChunk
+	(*FileChunkInfo)(nil),         // 11: quorum.pb.FileChunkInfo
+	(*FileManifest)(nil),          // 12: quorum.pb.FileManifest
+	(*anypb.Any)(nil),             // 13: google.protobuf.Any
+	(*timestamppb.Timestamp)(nil), // 14: google.protobuf.Timestamp
 }
 var file_activity_stream_proto_depIdxs = []int32{
-	10, // 0: quorum.pb.AnyObj.any:type_name -> google.protobuf.Any
+	13, // 0: quorum.pb.AnyObj.any:type_name -> google.protobuf.Any
 	2,  // 1: quorum.pb.Object.attachments:type_name -> quorum.pb.Object
 	2,  // 2: quorum.pb.Object.attributedTo:type_name -> quorum.pb.Object
 	2,  // 3: quorum.pb.Object.audience:type_name -> quorum.pb.Object
 	2,  // 4: quorum.pb.Object.context:type_name -> quorum.pb.Object
-	11, // 5: quorum.pb.Object.endtime:type_name -> google.protobuf.Timestamp
+	14, // 5: quorum.pb.Object.endtime:type_name -> google.protobuf.Timestamp
 	2,  // 6: quorum.pb.Object.generator:type_name -> quorum.pb.Object
 	2,  // 7: quorum.pb.Object.icon:type_name -> quorum.pb.Object
 	5,  // 8: quorum.pb.Object.image:type_name -> quorum.pb.Image
 	4,  // 9: quorum.pb.Object.inreplyto:type_name -> quorum.pb.Reply
 	2,  // 10: quorum.pb.Object.location:type_name -> quorum.pb.Object
 	2,  // 11: quorum.pb.Object.preview:type_name -> quorum.pb.Object
-	11, // 12: quorum.pb.Object.published:type_name -> google.protobuf.Timestamp
+	14, // 12: quorum.pb.Object.published:type_name -> google.protobuf.Timestamp
 	2,  // 13: quorum.pb.Object.replies:type_name -> quorum.pb.Object
-	11, // 14: quorum.pb.Object.startTime:type_name -> google.protobuf.Timestamp
+	14, // 14: quorum.pb.Object.startTime:type_name -> google.protobuf.Timestamp
 	2,  // 15: quorum.pb.Object.tag:type_name -> quorum.pb.Object
-	11, // 16: quorum.pb.Object.updated:type_name -> google.protobuf.Timestamp
+	14, // 16: quorum.pb.Object.updated:type_name -> google.protobuf.Timestamp
 	3,  // 17: quorum.pb.Object.url:type_name -> quorum.pb.Link
 	2,  // 18: quorum.pb.Object.to:type_name -> quorum.pb.Object
 	2,  // 19: quorum.pb.Object.bto:type_name -> quorum.pb.Object
@@ -1454,42 +1744,45 @@ var file_activity_stream_proto_depIdxs = []int32{
 	2,  // 21: quorum.pb.Object.bcc:type_name -> quorum.pb.Object
 	6,  // 22: quorum.pb.Object.file:type_name -> quorum.pb.File
 	2,  // 23: quorum.pb.Link.preview:type_name -> quorum.pb.Object
-	0,  // 24: quorum.pb.File.compression:type_name -> quorum.pb.File.Compression
-	5,  // 25: quorum.pb.Person.image:type_name -> quorum.pb.Image
-	8,  // 26: quorum.pb.Person.wallet:type_name -> quorum.pb.Payment
-	2,  // 27: quorum.pb.Activity.attachments:type_name -> quorum.pb.Object
-	2,  // 28: quorum.pb.Activity.attributedTo:type_name -> quorum.pb.Object
-	2,  // 29: quorum.pb.Activity.audience:type_name -> quorum.pb.Object
-	2,  // 30: quorum.pb.Activity.context:type_name -> quorum.pb.Object
-	11, // 31: quorum.pb.Activity.endtime:type_name -> google.protobuf.Timestamp
-	2,  // 32: quorum.pb.Activity.generator:type_name -> quorum.pb.Object
-	2,  // 33: quorum.pb.Activity.icon:type_name -> quorum.pb.Object
-	2,  // 34: quorum.pb.Activity.image:type_name -> quorum.pb.Object
-	2,  // 35: quorum.pb.Activity.inReplyTo:type_name -> quorum.pb.Object
-	2,  // 36: quorum.pb.Activity.location:type_name -> quorum.pb.Object
-	2,  // 37: quorum.pb.Activity.preview:type_name -> quorum.pb.Object
-	11, // 38: quorum.pb.Activity.published:type_name -> google.protobuf.Timestamp
-	2,  // 39: quorum.pb.Activity.replies:type_name -> quorum.pb.Object
-	11, // 40: quorum.pb.Activity.startTime:type_name -> google.protobuf.Timestamp
-	2,  // 41: quorum.pb.Activity.tag:type_name -> quorum.pb.Object
-	11, // 42: quorum.pb.Activity.updated:type_name -> google.protobuf.Timestamp
-	3,  // 43: quorum.pb.Activity.url:type_name -> quorum.pb.Link
-	2,  // 44: quorum.pb.Activity.to:type_name -> quorum.pb.Object
-	2,  // 45: quorum.pb.Activity.bto:type_name -> quorum.pb.Object
-	2,  // 46: quorum.pb.Activity.cc:type_name -> quorum.pb.Object
-	2,  // 47: quorum.pb.Activity.bcc:type_name -> quorum.pb.Object
-	2,  // 48: quorum.pb.Activity.actor:type_name -> quorum.pb.Object
-	2,  // 49: quorum.pb.Activity.object:type_name -> quorum.pb.Object
-	2,  // 50: quorum.pb.Activity.target:type_name -> quorum.pb.Object
-	2,  // 51: quorum.pb.Activity.result:type_name -> quorum.pb.Object
-	2,  // 52: quorum.pb.Activity.origin:type_name -> quorum.pb.Object
-	2,  // 53: quorum.pb.Activity.instrument:type_name -> quorum.pb.Object
-	7,  // 54: quorum.pb.Activity.person:type_name -> quorum.pb.Person
-	55, // [55:55] is the sub-list for method output_type
-	55, // [55:55] is the sub-list for method input_type
-	55, // [55:55] is the sub-list for extension type_name
-	55, // [55:55] is the sub-list for extension extendee
-	0,  // [0:55] is the sub-list for field type_name
+	0,  // 24: quorum.pb.Image.compression:type_name -> quorum.pb.File.Compression
+	0,  // 25: quorum.pb.File.compression:type_name -> quorum.pb.File.Compression
+	5,  // 26: quorum.pb.Person.image:type_name -> quorum.pb.Image
+	8,  // 27: quorum.pb.Person.wallet:type_name -> quorum.pb.Payment
+	2,  // 28: quorum.pb.Activity.attachments:type_name -> quorum.pb.Object
+	2,  // 29: quorum.pb.Activity.attributedTo:type_name -> quorum.pb.Object
+	2,  // 30: quorum.pb.Activity.audience:type_name -> quorum.pb.Object
+	2,  // 31: quorum.pb.Activity.context:type_name -> quorum.pb.Object
+	14, // 32: quorum.pb.Activity.endtime:type_name -> google.protobuf.Timestamp
+	2,  // 33: quorum.pb.Activity.generator:type_name -> quorum.pb.Object
+	2,  // 34: quorum.pb.Activity.icon:type_name -> quorum.pb.Object
+	2,  // 35: quorum.pb.Activity.image:type_name -> quorum.pb.Object
+	2,  // 36: quorum.pb.Activity.inReplyTo:type_name -> quorum.pb.Object
+	2,  // 37: quorum.pb.Activity.location:type_name -> quorum.pb.Object
+	2,  // 38: quorum.pb.Activity.preview:type_name -> quorum.pb.Object
+	14, // 39: quorum.pb.Activity.published:type_name -> google.protobuf.Timestamp
+	2,  // 40: quorum.pb.Activity.replies:type_name -> quorum.pb.Object
+	14, // 41: quorum.pb.Activity.startTime:type_name -> google.protobuf.Timestamp
+	2,  // 42: quorum.pb.Activity.tag:type_name -> quorum.pb.Object
+	14, // 43: quorum.pb.Activity.updated:type_name -> google.protobuf.Timestamp
+	3,  // 44: quorum.pb.Activity.url:type_name -> quorum.pb.Link
+	2,  // 45: quorum.pb.Activity.to:type_name -> quorum.pb.Object
+	2,  // 46: quorum.pb.Activity.bto:type_name -> quorum.pb.Object
+	2,  // 47: quorum.pb.Activity.cc:type_name -> quorum.pb.Object
+	2,  // 48: quorum.pb.Activity.bcc:type_name -> quorum.pb.Object
+	2,  // 49: quorum.pb.Activity.actor:type_name -> quorum.pb.Object
+	2,  // 50: quorum.pb.Activity.object:type_name -> quorum.pb.Object
+	2,  // 51: quorum.pb.Activity.target:type_name -> quorum.pb.Object
+	2,  // 52: quorum.pb.Activity.result:type_name -> quorum.pb.Object
+	2,  // 53: quorum.pb.Activity.origin:type_name -> quorum.pb.Object
+	2,  // 54: quorum.pb.Activity.instrument:type_name -> quorum.pb.Object
+	7,  // 55: quorum.pb.Activity.person:type_name -> quorum.pb.Person
+	0,  // 56: quorum.pb.FileManifest.compression:type_name -> quorum.pb.File.Compression
+	11, // 57: quorum.pb.FileManifest.chunks:type_name -> quorum.pb.FileChunkInfo
+	58, // [58:58] is the sub-list for method output_type
+	58, // [58:58] is the sub-list for method input_type
+	58, // [58:58] is the sub-list for extension type_name
+	58, // [58:58] is the sub-list for extension extendee
+	0,  // [0:58] is the sub-list for field type_name
 }
 
 func init() { file_activity_stream_proto_init() }
@@ -1606,6 +1899,42 @@ func file_activity_stream_proto_init() {
 				return nil
 			}
 		}
+		file_activity_stream_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FileChunk); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_activity_stream_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FileChunkInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_activity_stream_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FileManifest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -1613,7 +1942,7 @@ func file_activity_stream_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_activity_stream_proto_rawDesc,
 			NumEnums:      1,
-			NumMessages:   9,
+			NumMessages:   12,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
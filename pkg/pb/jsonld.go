@@ -0,0 +1,355 @@
+package pb
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	ActivityStreamsContext = "https://www.w3.org/ns/activitystreams"
+	RumNamespace           = "https://rumsystem.net/ns#"
+
+	// a Reply is rendered as rum://group/<groupid>/trx/<trxid>, or rum://trx/<trxid> without group
+	RumIRIScheme = "rum://"
+)
+
+// AS2 property names of the proto fields which are not named as in ActivityStreams,
+// fields without an AS2 equivalent use the rum: namespace
+var jsonldPropertyNames = map[string]string{
+	"attachments": "attachment",
+	"endtime":     "endTime",
+	"inreplyto":   "inReplyTo",
+	"file":        "rum:file",
+	"person":      "rum:person",
+	"wallet":      "rum:wallet",
+	"compression": "rum:compression",
+	"compressed":  "rum:compressed",
+}
+
+// the AS2 type of messages without a type field
+var jsonldMessageTypes = map[protoreflect.FullName]string{
+	"quorum.pb.Image":   "Image",
+	"quorum.pb.Link":    "Link",
+	"quorum.pb.File":    "Document",
+	"quorum.pb.Person":  "Person",
+	"quorum.pb.Payment": "rum:Payment",
+}
+
+// ToJSONLD renders an Activity, Object, Link, Image, File or Person as ActivityStreams 2.0 JSON-LD.
+// Nested objects which only have an id are rendered as IRIs, timestamps as xsd:dateTime
+// and bytes as base64.
+func ToJSONLD(msg proto.Message) ([]byte, error) {
+	doc, err := ToJSONLDMap(msg)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// ToJSONLDMap is ToJSONLD without the final json encoding
+func ToJSONLDMap(msg proto.Message) (map[string]interface{}, error) {
+	m := msg.ProtoReflect()
+	if !isJSONLDMessage(m.Descriptor()) {
+		return nil, fmt.Errorf("%s can not be converted to JSON-LD", m.Descriptor().FullName())
+	}
+	doc := messageToJSONLD(m)
+	doc["@context"] = []interface{}{ActivityStreamsContext, map[string]interface{}{"rum": RumNamespace}}
+	return doc, nil
+}
+
+// FromJSONLD parses ActivityStreams 2.0 JSON-LD in compact form into msg, which must be
+// an Activity, Object, Link, Image, File or Person
+func FromJSONLD(data []byte, msg proto.Message) error {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	return FromJSONLDMap(doc, msg)
+}
+
+// FromJSONLDMap is FromJSONLD on an already decoded json document
+func FromJSONLDMap(doc map[string]interface{}, msg proto.Message) error {
+	m := msg.ProtoReflect()
+	if !isJSONLDMessage(m.Descriptor()) {
+		return fmt.Errorf("%s can not be converted from JSON-LD", m.Descriptor().FullName())
+	}
+	if err := checkJSONLDContext(doc["@context"]); err != nil {
+		return err
+	}
+	return messageFromJSONLD(doc, m)
+}
+
+func isJSONLDMessage(md protoreflect.MessageDescriptor) bool {
+	switch md.FullName() {
+	case "quorum.pb.Activity", "quorum.pb.Object", "quorum.pb.Link", "quorum.pb.Image", "quorum.pb.File", "quorum.pb.Person":
+		return true
+	}
+	return false
+}
+
+func checkJSONLDContext(context interface{}) error {
+	switch c := context.(type) {
+	case nil:
+		return nil
+	case string:
+		if c == ActivityStreamsContext {
+			return nil
+		}
+	case []interface{}:
+		for _, item := range c {
+			if s, ok := item.(string); ok && s == ActivityStreamsContext {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("@context must include %s", ActivityStreamsContext)
+}
+
+func jsonldPropertyName(md protoreflect.MessageDescriptor, fd protoreflect.FieldDescriptor) string {
+	name := string(fd.Name())
+	switch {
+	case md.FullName() == "quorum.pb.Payment" && name == "type":
+		return "rum:paymentType"
+	case name == "content" && fd.Kind() == protoreflect.BytesKind:
+		return "rum:content"
+	}
+	if property, ok := jsonldPropertyNames[name]; ok {
+		return property
+	}
+	return name
+}
+
+func messageToJSONLD(m protoreflect.Message) map[string]interface{} {
+	md := m.Descriptor()
+	doc := make(map[string]interface{})
+	if t, ok := jsonldMessageTypes[md.FullName()]; ok {
+		doc["type"] = t
+	}
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		v := m.Get(fd)
+		name := jsonldPropertyName(md, fd)
+		if fd.IsList() {
+			list := v.List()
+			values := make([]interface{}, 0, list.Len())
+			for j := 0; j < list.Len(); j++ {
+				values = append(values, valueToJSONLD(fd, list.Get(j)))
+			}
+			doc[name] = values
+		} else {
+			doc[name] = valueToJSONLD(fd, v)
+		}
+	}
+	return doc
+}
+
+func valueToJSONLD(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return v.String()
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(v.Bytes())
+	case protoreflect.Uint32Kind:
+		return v.Uint()
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return int32(v.Enum())
+	case protoreflect.MessageKind:
+		m := v.Message()
+		switch msg := m.Interface().(type) {
+		case *timestamppb.Timestamp:
+			return msg.AsTime().UTC().Format(time.RFC3339Nano)
+		case *Reply:
+			return ReplyToIRI(msg)
+		case *Object:
+			if msg.Id != "" && proto.Equal(msg, &Object{Id: msg.Id}) {
+				return msg.Id
+			}
+		}
+		return messageToJSONLD(m)
+	}
+	return v.Interface()
+}
+
+func messageFromJSONLD(doc map[string]interface{}, m protoreflect.Message) error {
+	md := m.Descriptor()
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		value, ok := doc[jsonldPropertyName(md, fd)]
+		if !ok || value == nil {
+			continue
+		}
+		if fd.IsList() {
+			values, ok := value.([]interface{})
+			if !ok {
+				//AS2 allows a single value for a multi valued property
+				values = []interface{}{value}
+			}
+			list := m.Mutable(fd).List()
+			for _, item := range values {
+				v, err := valueFromJSONLD(fd, item, list.NewElement)
+				if err != nil {
+					return err
+				}
+				list.Append(v)
+			}
+			continue
+		}
+		if values, ok := value.([]interface{}); ok {
+			if len(values) == 0 {
+				continue
+			}
+			value = values[0]
+		}
+		v, err := valueFromJSONLD(fd, value, func() protoreflect.Value { return m.NewField(fd) })
+		if err != nil {
+			return err
+		}
+		m.Set(fd, v)
+	}
+	return nil
+}
+
+func valueFromJSONLD(fd protoreflect.FieldDescriptor, value interface{}, newValue func() protoreflect.Value) (protoreflect.Value, error) {
+	property := string(fd.Name())
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		s, ok := value.(string)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("%s must be a string", property)
+		}
+		return protoreflect.ValueOfString(s), nil
+	case protoreflect.BytesKind:
+		s, ok := value.(string)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("%s must be a base64 string", property)
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("%s: %s", property, err)
+		}
+		return protoreflect.ValueOfBytes(b), nil
+	case protoreflect.Uint32Kind:
+		n, ok := value.(float64)
+		if !ok || n < 0 || n != float64(uint32(n)) {
+			return protoreflect.Value{}, fmt.Errorf("%s must be a non negative integer", property)
+		}
+		return protoreflect.ValueOfUint32(uint32(n)), nil
+	case protoreflect.BoolKind:
+		b, ok := value.(bool)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("%s must be a boolean", property)
+		}
+		return protoreflect.ValueOfBool(b), nil
+	case protoreflect.EnumKind:
+		s, ok := value.(string)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("%s must be a string", property)
+		}
+		ev := fd.Enum().Values().ByName(protoreflect.Name(s))
+		if ev == nil {
+			return protoreflect.Value{}, fmt.Errorf("unknown %s %s", property, s)
+		}
+		return protoreflect.ValueOfEnum(ev.Number()), nil
+	case protoreflect.MessageKind:
+		v := newValue()
+		switch msg := v.Message().Interface().(type) {
+		case *timestamppb.Timestamp:
+			s, ok := value.(string)
+			if !ok {
+				return protoreflect.Value{}, fmt.Errorf("%s must be a dateTime string", property)
+			}
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return protoreflect.Value{}, fmt.Errorf("%s: %s", property, err)
+			}
+			msg.Seconds, msg.Nanos = t.Unix(), int32(t.Nanosecond())
+			return v, nil
+		case *Reply:
+			iri, ok := value.(string)
+			if !ok {
+				if obj, isobj := value.(map[string]interface{}); isobj {
+					iri, ok = obj["id"].(string)
+				}
+			}
+			if !ok {
+				return protoreflect.Value{}, fmt.Errorf("%s must be an IRI", property)
+			}
+			proto.Merge(msg, ReplyFromIRI(iri))
+			return v, nil
+		}
+		switch obj := value.(type) {
+		case string:
+			//an IRI in place of the object
+			if err := setJSONLDLinkField(v.Message(), obj); err != nil {
+				return protoreflect.Value{}, fmt.Errorf("%s: %s", property, err)
+			}
+		case map[string]interface{}:
+			if err := messageFromJSONLD(obj, v.Message()); err != nil {
+				return protoreflect.Value{}, err
+			}
+		default:
+			return protoreflect.Value{}, fmt.Errorf("%s must be an object or an IRI", property)
+		}
+		return v, nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported field %s", property)
+}
+
+func setJSONLDLinkField(m protoreflect.Message, iri string) error {
+	var name protoreflect.Name
+	switch m.Descriptor().FullName() {
+	case "quorum.pb.Link":
+		name = "href"
+	case "quorum.pb.Image", "quorum.pb.File":
+		name = "url"
+	default:
+		name = "id"
+	}
+	fd := m.Descriptor().Fields().ByName(name)
+	if fd == nil {
+		return fmt.Errorf("%s can not be an IRI", m.Descriptor().FullName())
+	}
+	m.Set(fd, protoreflect.ValueOfString(iri))
+	return nil
+}
+
+// ReplyToIRI returns the rum:// IRI of the trx a Reply refers to
+func ReplyToIRI(reply *Reply) string {
+	if strings.Contains(reply.Trxid, "://") {
+		return reply.Trxid
+	}
+	if reply.Groupid == "" {
+		return fmt.Sprintf("%strx/%s", RumIRIScheme, reply.Trxid)
+	}
+	return fmt.Sprintf("%sgroup/%s/trx/%s", RumIRIScheme, reply.Groupid, reply.Trxid)
+}
+
+// ReplyFromIRI parses a rum:// IRI, any other IRI is kept as is in Reply.Trxid
+func ReplyFromIRI(iri string) *Reply {
+	if !strings.HasPrefix(iri, RumIRIScheme) {
+		return &Reply{Trxid: iri}
+	}
+	parts := strings.Split(strings.TrimPrefix(iri, RumIRIScheme), "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "trx":
+		return &Reply{Trxid: parts[1]}
+	case len(parts) == 4 && parts[0] == "group" && parts[2] == "trx":
+		return &Reply{Groupid: parts[1], Trxid: parts[3]}
+	}
+	return &Reply{Trxid: iri}
+}
@@ -0,0 +1,100 @@
+package pb
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestJSONLDRoundTrip(t *testing.T) {
+	published := timestamppb.New(time.Date(2022, 7, 25, 16, 1, 35, 123000000, time.UTC))
+	activity := &Activity{
+		Type:      "Create",
+		Actor:     &Object{Id: "https://example.com/users/alice"},
+		Published: published,
+		To:        []*Object{{Id: ActivityStreamsContext + "#Public"}},
+		Object: &Object{
+			Id:        "0001",
+			Type:      "Note",
+			Content:   "test content",
+			Inreplyto: &Reply{Trxid: "b9a2e3f0-1111-4b80-81fb-d6347d0380b5", Groupid: "7c352591-f237-4b80-81fb-d6347d0380b5"},
+			Image:     []*Image{{Name: "a.png", MediaType: "image/png", Content: []byte{0x89, 'P', 'N', 'G'}}},
+			Url:       []*Link{{Href: "https://example.com/notes/1", Rel: []string{"canonical"}, Width: 640}},
+			File:      &File{Name: "a.txt.gz", Compression: File_gz, Content: []byte("gz")},
+		},
+		Person: &Person{Name: "alice", Wallet: []*Payment{{Id: "0x0000000000000000000000000000000000000001", Type: "mixin", Name: "alice"}}},
+	}
+
+	data, err := ToJSONLD(activity)
+	if err != nil {
+		t.Fatalf("ToJSONLD err: %s", err)
+	}
+
+	var doc map[string]interface{}
+	json.Unmarshal(data, &doc)
+	if doc["actor"] != "https://example.com/users/alice" {
+		t.Errorf("object with only an id should be an IRI, got %v", doc["actor"])
+	}
+	if doc["published"] != "2022-07-25T16:01:35.123Z" {
+		t.Errorf("published got %v", doc["published"])
+	}
+	object := doc["object"].(map[string]interface{})
+	if object["inReplyTo"] != "rum://group/7c352591-f237-4b80-81fb-d6347d0380b5/trx/b9a2e3f0-1111-4b80-81fb-d6347d0380b5" {
+		t.Errorf("inReplyTo got %v", object["inReplyTo"])
+	}
+
+	result := &Activity{}
+	if err := FromJSONLD(data, result); err != nil {
+		t.Fatalf("FromJSONLD err: %s", err)
+	}
+	if !proto.Equal(activity, result) {
+		t.Errorf("round trip mismatch:\n%v\n%v", activity, result)
+	}
+}
+
+func TestFromJSONLDActivityPub(t *testing.T) {
+	data := []byte(`{
+		"@context": ["https://www.w3.org/ns/activitystreams", {"sensitive": "as:sensitive"}],
+		"id": "https://example.com/users/alice/statuses/1/activity",
+		"type": "Create",
+		"actor": "https://example.com/users/alice",
+		"to": "https://www.w3.org/ns/activitystreams#Public",
+		"object": {
+			"id": "https://example.com/users/alice/statuses/1",
+			"type": "Note",
+			"content": "<p>hello</p>",
+			"inReplyTo": "https://example.com/users/bob/statuses/2",
+			"attachment": [{"type": "Document", "mediaType": "image/png", "url": "https://example.com/a.png"}],
+			"url": "https://example.com/@alice/1",
+			"sensitive": false
+		}
+	}`)
+
+	activity := &Activity{}
+	if err := FromJSONLD(data, activity); err != nil {
+		t.Fatalf("FromJSONLD err: %s", err)
+	}
+	if activity.Actor.Id != "https://example.com/users/alice" || len(activity.To) != 1 {
+		t.Errorf("actor or to not parsed: %v", activity)
+	}
+	obj := activity.Object
+	if obj.Content != "<p>hello</p>" || obj.Inreplyto.Trxid != "https://example.com/users/bob/statuses/2" {
+		t.Errorf("object not parsed: %v", obj)
+	}
+	if len(obj.Attachments) != 1 || obj.Attachments[0].MediaType != "image/png" {
+		t.Errorf("attachment not parsed: %v", obj.Attachments)
+	}
+	if len(obj.Url) != 1 || obj.Url[0].Href != "https://example.com/@alice/1" {
+		t.Errorf("url not parsed: %v", obj.Url)
+	}
+
+	if err := FromJSONLD([]byte(`{"@context": "https://example.com/ns", "type": "Note"}`), &Object{}); err == nil {
+		t.Errorf("document without activitystreams context accepted")
+	}
+	if err := FromJSONLD(data, &Trx{}); err == nil {
+		t.Errorf("FromJSONLD into Trx should fail")
+	}
+}